@@ -17,7 +17,7 @@ def check_pinfu(man, pin, sou, honors, player_wind, round_wind, win_tile_type, w
   if result.yaku is not None:
     for yaku in result.yaku:
       if yaku.name == "Pinfu":
-        cost = 1500 if config.is_dealer else 1000
-        return [json.dumps({'isPinfu':True,'cost':cost}).encode("utf-8")]
+        cost = result.cost['main']
+        return [json.dumps({'isPinfu':True,'cost':cost,'han':result.han,'fu':result.fu}).encode("utf-8")]
 
-  return [json.dumps({'isPinfu':False,'cost':0}).encode("utf-8")]
+  return [json.dumps({'isPinfu':False,'cost':0,'han':0,'fu':0}).encode("utf-8")]
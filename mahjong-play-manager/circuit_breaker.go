@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops calling an unhealthy service after threshold consecutive failures
+// and lets a single trial call through once cooldown has passed.
+type CircuitBreaker struct {
+	threshold int
+	cooldown time.Duration
+	state circuitState
+	failures int
+	openedAt time.Time
+	mux sync.Mutex
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, state: circuitClosed}
+}
+
+func (b *CircuitBreaker) Allow() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = circuitHalfOpen
+		logger.Info("circuit breaker half-open")
+		return true
+	case circuitHalfOpen:
+		return false
+	}
+	return true
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.state != circuitClosed {
+		logger.Info("circuit breaker closed")
+	}
+	b.state = circuitClosed
+	b.failures = 0
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		if b.state != circuitOpen {
+			logger.Warn("circuit breaker open", F("failures", b.failures))
+		}
+		b.state = circuitOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Abandon gives up a call that ended without telling whether the service is healthy.
+// An abandoned trial call leaves the breaker open so that the next call is the trial.
+func (b *CircuitBreaker) Abandon() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.state == circuitHalfOpen {
+		b.state = circuitOpen
+	}
+}
@@ -4,8 +4,8 @@
 package main
 
 import (
-	"log"
 	"net/http"
+	"sync"
 	"time"
 	"encoding/json"
 	"github.com/gorilla/websocket"
@@ -29,6 +29,7 @@ type Client struct {
 	conn *websocket.Conn
 	send chan []byte
 	playerId int
+	mux sync.Mutex
 }
 
 type Operator struct {
@@ -38,7 +39,10 @@ type Operator struct {
 
 func (c *Client) readPump(m *MahjongPlayManager) {
 	defer func() {
-		c.hub.unregister <- c
+		if playerId := c.hub.LeaveSeat(c); playerId != playerIdNone {
+			m.Resend(playerId)
+		}
+		c.hub.Unregister(c)
 		c.conn.Close()
 	}()
 	c.conn.SetReadLimit(maxMessageSize)
@@ -48,87 +52,15 @@ func (c *Client) readPump(m *MahjongPlayManager) {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error: %v", err)
+				logger.Warn("connection closed unexpectedly", F("error", err))
 			}
 			break
 		}
 		operator := c.parseOperator(message)
-		sendBroadCast := true
-		switch {
-		case operator.isStart():
-			m.InitRound()
-
-			m.SendMessageStart()
-		case operator.isDiscard():
-			discardedTile := m.DiscardTile(operator.Target)
-			canRon := m.CheckPinfuAndSetRon(discardedTile)
-			if canRon {
-				m.SendMessageDiscard(m.playerIdInTurn)
-				m.SendMessageDiscardOther(m.playerIdInTurn, discardedTile)
-			} else {
-				if m.CanDistributeTile() {
-					playerIdInTurnBefore := m.RotatePlayer()
-					m.DistributeTile()
-
-					m.SendMessageDiscard(playerIdInTurnBefore)
-					m.SendMessageDiscardOther(playerIdInTurnBefore, discardedTile)
-					m.SendMessageDrawn(discardedTile)
-				} else {
-					m.WaitNextMessage()
-
-					m.SendMessageDrawnRound(discardedTile)
-				}
-			}
-		case operator.isRon():
-			ronInfo := m.CalculateRonInfo(c.playerId)
-			m.UpdatePlayersPoint(ronInfo)
-			m.SetFirstPinfuOrder(c.playerId)
-			m.DealerWin(c.playerId)
-			m.WaitNextMessage()
-
-			m.SendMessageRon(ronInfo)
-		case operator.isSkip():
-			m.RotatePlayer()
-			if m.CanDistributeTile() {
-				m.DistributeTile()
-
-				m.SendMessageSkip()
-				m.SendMessageDrawn(tileIdNone)
-			} else {
-				m.WaitNextMessage()
-
-				m.SendMessageDrawnRound(tileIdNone)
-			}
-		case operator.isNext():
-			f := func() {
-				if m.continueGame() {
-					if m.IsDealerWin() {
-						m.NextSubRound()
-					} else {
-						m.RotateRound()
-						m.RotatePlayerWind()
-						m.ResetSubRound()
-					}
-					m.InitRound()
-
-					m.SendMessageNext()
-				} else {
-					result := m.CalculateResult()
-
-					m.SendMessageResult(result)
-				}
-			}
-			sendBroadCast = m.TriggerNextMessage(f)
-		case operator.isResult():
-			result := m.CalculateResult()
-
-			m.SendMessageResult(result)
-		default:
-			log.Println("not operated")
-			continue
-		}
-		if sendBroadCast {
-			c.hub.broadcast <- message
+		playerId := c.PlayerId()
+		if err := m.Submit(playerId, operator); err != nil {
+			logger.Info("operation rejected", F("playerId", playerId), F("operation", err.Operation), F("code", err.Code))
+			c.hub.SendTo(c, (&SendMessage{"error", err}).ToBytes())
 		}
 	}
 }
@@ -137,7 +69,7 @@ func (c *Client) parseOperator(message []byte) *Operator {
 	operator := Operator{"", tileIdNone}
 	err := json.Unmarshal(message, &operator)
 	if err == nil {
-		log.Println(operator)
+		logger.Debug("operation", F("playerId", c.PlayerId()), F("operation", operator.Operation), F("target", operator.Target))
 	}
 	return &operator
 }
@@ -199,6 +131,14 @@ func (o *Operator) isRon() bool {
 	return o.Operation == "ron"
 }
 
+func (o *Operator) isTsumo() bool {
+	return o.Operation == "tsumo"
+}
+
+func (o *Operator) isRiichi() bool {
+	return o.Operation == "riichi"
+}
+
 func (o *Operator) isSkip() bool {
 	return o.Operation == "skip"
 }
@@ -211,24 +151,45 @@ func (o *Operator) isResult() bool {
 	return o.Operation == "result"
 }
 
-// serveWs handles websocket requests from the peer.
-func serveWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
+func (o *Operator) isResume() bool {
+	return o.Operation == "resume"
+}
+
+// serveWs handles websocket requests from the peer, joining the room given by
+// the room query parameter or the default room. The rules query parameter names
+// the rule file of a room that does not exist yet.
+func serveWs(rooms *RoomManager, w http.ResponseWriter, r *http.Request) {
+	roomId := r.URL.Query().Get("room")
+	rules := r.URL.Query().Get("rules")
+	if roomId == "" {
+		roomId = defaultRoomId
+	}
+	if !roomIdPattern.MatchString(roomId) {
+		http.Error(w, "Invalid room", http.StatusBadRequest)
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println(err)
+		logger.Warn("upgrade failed", F("error", err))
 		return
 	}
-	log.Println("serveWs")
-	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256), playerId: hub.mahjongPlayManager.newPlayerNumber()}
-	client.hub.register <- client
-	if hub.mahjongPlayManager.isReady() {
-		hub.mahjongPlayManager.InitRound()
-		hub.mahjongPlayManager.SendMessageStart()
-		hub.broadcast <- []byte{}
+	room, err := rooms.Join(roomId, rules)
+	if err != nil {
+		logger.Warn("join failed", F("room", roomId), F("error", err))
+		conn.Close()
+		return
 	}
+	logger.Info("client connected", F("room", roomId))
+	hub := room.hub
+	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256), playerId: playerIdNone}
+	client.hub.Register(client)
+	room.Seat(client)
 
 	// Allow collection of memory referenced by the caller by doing all work in
 	// new goroutines.
 	go client.writePump()
-	go client.readPump(hub.mahjongPlayManager)
+	go func() {
+		client.readPump(room.manager)
+		rooms.Leave(room)
+	}()
 }
@@ -0,0 +1,182 @@
+package main
+
+import "strings"
+
+const (
+	winningMeldPair = -1
+)
+
+type MeldType int
+
+const (
+	MeldSequence MeldType = iota
+	MeldTriplet
+)
+
+type Meld struct {
+	Type MeldType `json:"type"`
+	Tiles []Tile `json:"tiles"`
+}
+
+// Decomposition is one way to read a winning hand as four melds and a pair.
+// WinningMeld is the index of the meld holding the winning tile, or winningMeldPair.
+type Decomposition struct {
+	Melds []*Meld `json:"melds"`
+	Pair []Tile `json:"pair"`
+	WinTile Tile `json:"winTile"`
+	WinningMeld int `json:"winningMeld"`
+}
+
+type kindMeld struct {
+	meldType MeldType
+	kind int
+}
+
+// Decompose lists every split of the hands plus the winning tile into four melds and a pair.
+// The same split is listed once for each distinct meld, or the pair, that the winning tile can be in.
+func Decompose(hands []Tile, winTile Tile) []*Decomposition {
+	if len(hands) != tileInHandNumber {
+		return nil
+	}
+	tiles := make([]Tile, 0, len(hands) + 1)
+	tiles = append(tiles, hands...)
+	tiles = append(tiles, winTile)
+	counts := tileKindCounts(tiles)
+
+	decompositions := []*Decomposition{}
+	for pairKind := range counts {
+		if counts[pairKind] < 2 {
+			continue
+		}
+		counts[pairKind] -= 2
+		for _, melds := range kindMeldSets(counts, 0, []kindMeld{}) {
+			if pairKind == winTile.Kind() {
+				decompositions = append(decompositions, newDecomposition(tiles, winTile, pairKind, melds, winningMeldPair))
+			}
+			for i, meld := range melds {
+				if meld.contains(winTile.Kind()) && (i == 0 || melds[i - 1] != meld) {
+					decompositions = append(decompositions, newDecomposition(tiles, winTile, pairKind, melds, i))
+				}
+			}
+		}
+		counts[pairKind] += 2
+	}
+	return decompositions
+}
+
+// kindMeldSets splits the counts from kind on into melds. Every kind is split at once
+// into its triplet and the sequences starting at it, so each set of melds appears once.
+func kindMeldSets(counts []int, kind int, melds []kindMeld) [][]kindMeld {
+	for kind < tileKindNumber && counts[kind] == 0 {
+		kind++
+	}
+	if kind == tileKindNumber {
+		return [][]kindMeld{append([]kindMeld{}, melds...)}
+	}
+	sets := [][]kindMeld{}
+	for triplets := 0; triplets*3 <= counts[kind]; triplets++ {
+		sequences := counts[kind] - triplets*3
+		if sequences > 0 && !canStartSequences(counts, kind, sequences) {
+			continue
+		}
+		next := append([]kindMeld{}, melds...)
+		counts[kind] -= triplets*3
+		for i := 0; i < triplets; i++ {
+			next = append(next, kindMeld{MeldTriplet, kind})
+		}
+		for i := 0; i < sequences; i++ {
+			counts[kind]--
+			counts[kind + 1]--
+			counts[kind + 2]--
+			next = append(next, kindMeld{MeldSequence, kind})
+		}
+		sets = append(sets, kindMeldSets(counts, kind + 1, next)...)
+		counts[kind] += triplets*3
+		for i := 0; i < sequences; i++ {
+			counts[kind]++
+			counts[kind + 1]++
+			counts[kind + 2]++
+		}
+	}
+	return sets
+}
+
+func canStartSequences(counts []int, kind int, sequences int) bool {
+	return kind < tileKindHonorStart && kind%tileInSuitNumber <= tileInSuitNumber - 3 && counts[kind + 1] >= sequences && counts[kind + 2] >= sequences
+}
+
+func (k kindMeld) contains(kind int) bool {
+	if k.meldType == MeldTriplet {
+		return k.kind == kind
+	}
+	return kind >= k.kind && kind <= k.kind + 2
+}
+
+func (k kindMeld) kinds() []int {
+	if k.meldType == MeldTriplet {
+		return []int{k.kind, k.kind, k.kind}
+	}
+	return []int{k.kind, k.kind + 1, k.kind + 2}
+}
+
+// newDecomposition gives the kind level split real tiles, putting the winning tile in winningMeld.
+func newDecomposition(tiles []Tile, winTile Tile, pairKind int, melds []kindMeld, winningMeld int) *Decomposition {
+	pool := make([][]Tile, tileKindNumber)
+	winPlaced := false
+	for _, t := range tiles {
+		if t == winTile && !winPlaced {
+			winPlaced = true
+			continue
+		}
+		pool[t.Kind()] = append(pool[t.Kind()], t)
+	}
+	winPlaced = false
+	take := func(kind int, isWinning bool) Tile {
+		if isWinning && !winPlaced && kind == winTile.Kind() {
+			winPlaced = true
+			return winTile
+		}
+		t := pool[kind][0]
+		pool[kind] = pool[kind][1:]
+		return t
+	}
+
+	d := &Decomposition{make([]*Meld, len(melds)), nil, winTile, winningMeld}
+	d.Pair = []Tile{take(pairKind, winningMeld == winningMeldPair), take(pairKind, winningMeld == winningMeldPair)}
+	for i, meld := range melds {
+		m := &Meld{meld.meldType, []Tile{}}
+		for _, kind := range meld.kinds() {
+			m.Tiles = append(m.Tiles, take(kind, i == winningMeld))
+		}
+		d.Melds[i] = m
+	}
+	return d
+}
+
+func (d *Decomposition) WinningTiles() []Tile {
+	if d.WinningMeld == winningMeldPair {
+		return d.Pair
+	}
+	return d.Melds[d.WinningMeld].Tiles
+}
+
+func (m *Meld) String() string {
+	return FormatTiles(m.Tiles)
+}
+
+// String writes the melds and then the pair, marking the group with the winning tile by *.
+func (d *Decomposition) String() string {
+	groups := []string{}
+	for i, m := range d.Melds {
+		g := m.String()
+		if i == d.WinningMeld {
+			g += "*"
+		}
+		groups = append(groups, g)
+	}
+	pair := FormatTiles(d.Pair)
+	if d.WinningMeld == winningMeldPair {
+		pair += "*"
+	}
+	return strings.Join(append(groups, pair), " ")
+}
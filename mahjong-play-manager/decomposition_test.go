@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func decompositionStrings(decompositions []*Decomposition) []string {
+	strs := []string{}
+	for _, d := range decompositions {
+		strs = append(strs, d.String())
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+func TestDecompose(t *testing.T) {
+	tests := []struct {
+		hands string
+		winTile string
+		want []string
+	}{
+		// three identical sequences are also three triplets
+		{"111222333m456p9s", "9s", []string{"111m 222m 333m 456p 99s*", "123m 123m 123m 456p 99s*"}},
+		{"111222333m45p99s", "6p", []string{"111m 222m 333m 456p* 99s", "123m 123m 123m 456p* 99s"}},
+		// the winning tile can complete either sequence
+		{"23456m789p123s55z", "4m", []string{"234m 456m* 789p 123s 55z", "234m* 456m 789p 123s 55z"}},
+		// the winning tile is the pair or a triplet
+		{"11123m456p789s11z", "1z", []string{"123m 456p 789s 111z* 11m"}},
+		{"2223334445556m", "6m", []string{"222m 333m 444m 555m 66m*", "222m 345m 345m 345m 66m*", "222m 345m 456m* 456m 33m", "234m 234m 234m 555m 66m*"}},
+	}
+	for _, test := range tests {
+		got := decompositionStrings(Decompose(mustParseTiles(t, test.hands), mustParseTiles(t, test.winTile)[0]))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s+%s: got %q, want %q", test.hands, test.winTile, got, test.want)
+		}
+	}
+}
+
+func TestDecomposeListsSameMeldOnce(t *testing.T) {
+	// the winning 3m is in one of two identical 123m, which is one way to win
+	decompositions := Decompose(mustParseTiles(t, "11223m456p789s99s"), mustParseTiles(t, "3m")[0])
+	want := []string{"123m* 123m 456p 789s 99s"}
+	if got := decompositionStrings(decompositions); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestDecomposeNotWinning(t *testing.T) {
+	if d := Decompose(mustParseTiles(t, "11456789m789p23s"), mustParseTiles(t, "5s")[0]); len(d) != 0 {
+		t.Fatalf("got %v", d)
+	}
+	if d := Decompose(mustParseTiles(t, "2344m"), mustParseTiles(t, "4m")[0]); d != nil {
+		t.Fatalf("got %v for a short hand", d)
+	}
+}
+
+// Every decomposition uses each tile once and puts the winning tile in the winning meld.
+func TestDecomposeUsesEveryTile(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 1000; i++ {
+		hands := randomTenpaiHands(r)
+		for _, kind := range winningKinds(hands) {
+			winTile := NewTile(kind, 0)
+			for containsTile(hands, winTile) {
+				winTile++
+			}
+			decompositions := Decompose(hands, winTile)
+			if len(decompositions) == 0 {
+				t.Fatalf("%s+%s: no decomposition", FormatTiles(hands), winTile)
+			}
+			for _, d := range decompositions {
+				used := map[Tile]int{}
+				for _, m := range d.Melds {
+					for _, tile := range m.Tiles {
+						used[tile]++
+					}
+				}
+				for _, tile := range d.Pair {
+					used[tile]++
+				}
+				if len(d.Melds) != 4 || len(used) != tileInHandNumber + 1 {
+					t.Fatalf("%s+%s: %s does not use every tile once", FormatTiles(hands), winTile, d)
+				}
+				if used[winTile] != 1 || !containsTile(d.WinningTiles(), winTile) {
+					t.Fatalf("%s+%s: %s does not mark the winning tile", FormatTiles(hands), winTile, d)
+				}
+			}
+		}
+	}
+}
+
+func containsTile(tiles []Tile, tile Tile) bool {
+	for _, t := range tiles {
+		if t == tile {
+			return true
+		}
+	}
+	return false
+}
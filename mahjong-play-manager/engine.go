@@ -0,0 +1,105 @@
+package main
+
+import "reflect"
+
+// command is an operation of a player waiting to be applied by the engine loop.
+type command struct {
+	playerId int
+	operator *Operator
+	reply chan *OperationError
+}
+
+// Run applies the submitted operations and timeouts one by one on the calling goroutine, which
+// then owns the state of the game. When the messages of the seats have changed they are copied
+// and passed to outbound, in the order the operations were applied.
+func (m *MahjongPlayManager) Run(outbound func(messages [][]byte)) {
+	for {
+		select {
+		case c := <-m.commands:
+			err := m.Operate(c.playerId, c.operator)
+			c.reply <- err
+			if err == nil {
+				m.sendChanged(outbound)
+			}
+		case window := <-m.timeouts:
+			if m.TimeoutRonWindow(window) {
+				m.sendChanged(outbound)
+			}
+		case playerId := <-m.resends:
+			for _, messages := range m.SeatState(playerId) {
+				outbound(messages)
+			}
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// Submit hands an operation to the engine loop and waits until it is applied or rejected.
+func (m *MahjongPlayManager) Submit(playerId int, o *Operator) *OperationError {
+	c := &command{playerId, o, make(chan *OperationError, 1)}
+	select {
+	case m.commands <- c:
+	case <-m.quit:
+		return newOperationError(o, errorCodeClosed, "the room is closed")
+	}
+	return <-c.reply
+}
+
+// Resend asks the engine loop to bring a seat taken over during the game up to date.
+func (m *MahjongPlayManager) Resend(playerId int) {
+	select {
+	case m.resends <- playerId:
+	case <-m.quit:
+	}
+}
+
+// Stop ends the engine loop. Operations submitted afterwards are rejected.
+func (m *MahjongPlayManager) Stop() {
+	close(m.quit)
+}
+
+// sendChanged passes the messages on unless they are the same as the last ones passed,
+// as when a player answers a discard that others still have to answer.
+func (m *MahjongPlayManager) sendChanged(outbound func(messages [][]byte)) {
+	snapshot := m.Snapshot()
+	if m.lastSnapshot != nil && reflect.DeepEqual(snapshot, m.lastSnapshot) {
+		return
+	}
+	m.lastSnapshot = snapshot
+	outbound(snapshot)
+}
+
+// Snapshot is the outbound message of each seat at this moment.
+func (m *MahjongPlayManager) Snapshot() [][]byte {
+	messages := make([][]byte, playerNumber)
+	for i, s := range m.sendMessages {
+		messages[i] = s.ToBytes()
+	}
+	return messages
+}
+
+// SeatState is what a seat taken over during the game needs to catch up, as messages for that
+// seat only: its hands, the rivers and whether it can ron during a hand, and the last message
+// when the table is paused or the hand is over.
+func (m *MahjongPlayManager) SeatState(playerId int) [][][]byte {
+	state := [][][]byte{}
+	seatMessage := func(s *SendMessage) [][]byte {
+		messages := make([][]byte, playerNumber)
+		messages[playerId] = s.ToBytes()
+		return messages
+	}
+	switch m.phase {
+	case PhaseWaitingPlayers:
+		return state
+	case PhaseDealing, PhaseWaitingDiscard, PhaseWaitingRon:
+		p := m.playerInfos[playerId]
+		canRon := m.phase == PhaseWaitingRon && p.PinfuInfo.CanWin() && !m.HasDecided(playerId)
+		playInfo := &PlayInfo{m.round, p, m.GenerateEachPlayerIds(playerId), m.GenerateEachWinds(playerId), m.GenerateEachPoints(playerId), m.wallReveal.Commitment, m.riichiSticks, m.GenerateEachRivers(playerId), canRon}
+		state = append(state, seatMessage(&SendMessage{"seatState", playInfo}))
+		if !m.paused {
+			return state
+		}
+	}
+	return append(state, seatMessage(m.sendMessages[playerId]))
+}
@@ -0,0 +1,43 @@
+package main
+
+// IsFuriten tells whether the player cannot win by ron under a ruleset that enforces furiten:
+// a wait of the hands is in the river, a discard that completed the hands was passed since
+// the last discard of the player, or one was passed after riichi.
+func (m *MahjongPlayManager) IsFuriten(p *PlayerInfo) bool {
+	if !m.ruleset.Furiten {
+		return false
+	}
+	if p.TemporaryFuriten || p.RiichiFuriten {
+		return true
+	}
+	waits := winningKinds(p.Hands)
+	for _, t := range p.River {
+		for _, kind := range waits {
+			if t.Kind() == kind {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// markPassedDiscard makes the players whose hands the last discard completed furiten, since
+// nobody won it. The discard counts whether or not the hands had a yaku with it.
+func (m *MahjongPlayManager) markPassedDiscard() {
+	if !m.ruleset.Furiten {
+		return
+	}
+	for i, p := range m.playerInfos {
+		if i == m.playerIdInTurn {
+			continue
+		}
+		for _, kind := range winningKinds(p.Hands) {
+			if kind == m.discardedTile.Kind() {
+				p.TemporaryFuriten = true
+				if p.Riichi {
+					p.RiichiFuriten = true
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+)
+
+// newFuritenTestManager starts a game under furiten where the seat two after the player in turn
+// waits on 6p and 9p, the next seat on 3p and 6p, and the others have no wait.
+func newFuritenTestManager(t *testing.T) (*MahjongPlayManager, int, int) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	ruleset.Furiten = true
+	ruleset.RonTimeout = 0
+	m := &MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	operateWant(t, m, 0, &Operator{"start", tileIdNone}, "", PhaseWaitingDiscard)
+	other := (m.playerIdInTurn + 1) % playerNumber
+	waiter := (m.playerIdInTurn + 2) % playerNumber
+	for _, p := range m.playerInfos {
+		p.Hands = mustParseTiles(t, "1479m258p369s1234z")
+	}
+	m.playerInfos[waiter].Hands = mustParseTiles(t, "123m456p789s1178p")
+	m.playerInfos[other].Hands = mustParseTiles(t, "123m456s789s2245p")
+	if err := m.UpdateWinningTables(0, 1, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	return m, other, waiter
+}
+
+// discardDrawn makes the player in turn draw the tile of notation and discard it.
+func discardDrawn(t *testing.T, m *MahjongPlayManager, notation string, phase Phase) {
+	t.Helper()
+	m.playerInfos[m.playerIdInTurn].DrawnTile = mustParseTiles(t, notation)[0]
+	operateWant(t, m, m.playerIdInTurn, &Operator{"discard", tileIdNone}, "", phase)
+}
+
+func TestFuriten(t *testing.T) {
+	tests := []struct {
+		name string
+		setUp func(m *MahjongPlayManager, p *PlayerInfo)
+		wantRon bool
+	}{
+		{"no furiten", func(m *MahjongPlayManager, p *PlayerInfo) {}, true},
+		{"a wait in the river", func(m *MahjongPlayManager, p *PlayerInfo) {
+			p.River = mustParseTiles(t, "9p")
+		}, false},
+		{"another tile in the river", func(m *MahjongPlayManager, p *PlayerInfo) {
+			p.River = mustParseTiles(t, "5p")
+		}, true},
+		{"a win passed in the go-around", func(m *MahjongPlayManager, p *PlayerInfo) {
+			p.TemporaryFuriten = true
+		}, false},
+		{"a win passed after riichi", func(m *MahjongPlayManager, p *PlayerInfo) {
+			p.RiichiFuriten = true
+		}, false},
+		{"the rule off", func(m *MahjongPlayManager, p *PlayerInfo) {
+			m.ruleset.Furiten = false
+			p.River = mustParseTiles(t, "9p")
+		}, true},
+	}
+	for _, test := range tests {
+		m, _, waiter := newFuritenTestManager(t)
+		p := m.playerInfos[waiter]
+		test.setUp(m, p)
+		// the other seat can win the discard, so the ron window opens either way
+		discardDrawn(t, m, "6p", PhaseWaitingRon)
+		if p.PinfuInfo.CanWin() != test.wantRon {
+			t.Errorf("%s: got can win %t", test.name, p.PinfuInfo.CanWin())
+		}
+		if info := m.sendMessages[waiter].Values.(*DiscardedTileInfo); info.CanRon != test.wantRon {
+			t.Errorf("%s: got canRon %t in the discard message", test.name, info.CanRon)
+		}
+		err := m.ValidateOperation(waiter, &Operator{"ron", tileIdNone})
+		switch {
+		case test.wantRon && err != nil:
+			t.Errorf("%s: got %v", test.name, err)
+		case !test.wantRon && (err == nil || err.Code != errorCodeFuriten):
+			t.Errorf("%s: got %v, want %s", test.name, err, errorCodeFuriten)
+		}
+	}
+}
+
+// Passing a win makes the seat furiten until its own next discard, or to the end of the hand in riichi.
+func TestPassedDiscardFuriten(t *testing.T) {
+	tests := []struct {
+		riichi bool
+		wantRon bool
+	}{
+		{false, true},
+		{true, false},
+	}
+	for _, test := range tests {
+		m, other, waiter := newFuritenTestManager(t)
+		p := m.playerInfos[waiter]
+		p.Riichi = test.riichi
+		discardDrawn(t, m, "6p", PhaseWaitingRon)
+		operateWant(t, m, waiter, &Operator{"skip", tileIdNone}, "", PhaseWaitingRon)
+		operateWant(t, m, other, &Operator{"skip", tileIdNone}, "", PhaseWaitingDiscard)
+		if !p.TemporaryFuriten || p.RiichiFuriten != test.riichi {
+			t.Fatalf("riichi %t: got temporary %t and riichi %t furiten after passing", test.riichi, p.TemporaryFuriten, p.RiichiFuriten)
+		}
+
+		// still furiten in the same go-around
+		discardDrawn(t, m, "9p", PhaseWaitingDiscard)
+		if m.playerIdInTurn != waiter {
+			t.Fatalf("got player %d in turn, want %d", m.playerIdInTurn, waiter)
+		}
+		discardDrawn(t, m, "7z", PhaseWaitingDiscard)
+		if p.TemporaryFuriten {
+			t.Fatalf("riichi %t: temporary furiten kept after the own discard", test.riichi)
+		}
+
+		phase := PhaseWaitingDiscard
+		if test.wantRon {
+			phase = PhaseWaitingRon
+		}
+		discardDrawn(t, m, "9p", phase)
+		if p.PinfuInfo.CanWin() != test.wantRon {
+			t.Errorf("riichi %t: got can win %t after the own discard", test.riichi, p.PinfuInfo.CanWin())
+		}
+	}
+}
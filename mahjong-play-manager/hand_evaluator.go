@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	tileKindNumber = 34
+	tileKindHonorStart = 27
+	tileKindWindStart = 27
+	tileKindDragonStart = 31
+	tileInSuitNumber = 9
+	hanPinfu = 1
+)
+
+// HandEvaluator decides whether the hands plus the winning tile make a winning hand,
+// won as situation tells. It returns an error instead of a result when the hand could not be evaluated.
+type HandEvaluator interface {
+	Evaluate(ctx context.Context, hands []Tile, winTile Tile, situation WinSituation, playerWind Wind, roundWind Wind) (*PinfuInfo, error)
+}
+
+const (
+	evaluatorNative = "native"
+	evaluatorHTTP = "http"
+	evaluatorMock = "mock"
+)
+
+type HandEvaluatorConfig struct {
+	Backend string
+	CalculatorURL string
+	MockScript string
+	Timeout time.Duration
+	Retries int
+	Backoff time.Duration
+	BreakerThreshold int
+	BreakerCooldown time.Duration
+	Ruleset *Ruleset
+}
+
+func NewHandEvaluator(c *HandEvaluatorConfig) (HandEvaluator, error) {
+	switch c.Backend {
+	case evaluatorNative:
+		return &NativeHandEvaluator{c.Ruleset}, nil
+	case evaluatorHTTP:
+		if !c.Ruleset.IsPinfuOnly() {
+			return nil, fmt.Errorf("%s evaluator supports only the %s yaku set", evaluatorHTTP, yakuSetPinfu)
+		}
+		if c.Ruleset.Tsumo || c.Ruleset.Riichi {
+			return nil, fmt.Errorf("%s evaluator supports only ron wins without riichi", evaluatorHTTP)
+		}
+		return NewHTTPHandEvaluator(c), nil
+	case evaluatorMock:
+		return LoadMockHandEvaluator(c.MockScript)
+	}
+	return nil, fmt.Errorf("unknown evaluator: %s", c.Backend)
+}
+
+// NativeHandEvaluator evaluates the yaku of the ruleset in process without the hands-calculation service.
+type NativeHandEvaluator struct {
+	ruleset *Ruleset
+}
+
+func (e *NativeHandEvaluator) Evaluate(ctx context.Context, hands []Tile, winTile Tile, situation WinSituation, playerWind Wind, roundWind Wind) (*PinfuInfo, error) {
+	return e.ruleset.EvaluateWin(hands, winTile, situation, playerWind, roundWind), nil
+}
+
+func tileKindCounts(tiles []Tile) []int {
+	counts := make([]int, tileKindNumber)
+	for _, t := range tiles {
+		counts[t.Kind()]++
+	}
+	return counts
+}
+
+// IsPinfu reports whether the split is all sequences with a pair that is not yakuhai,
+// won on a two-sided wait.
+func (d *Decomposition) IsPinfu(playerWind Wind, roundWind Wind) bool {
+	if isYakuhaiKind(d.Pair[0].Kind(), playerWind, roundWind) || d.WinningMeld == winningMeldPair {
+		return false
+	}
+	for _, m := range d.Melds {
+		if m.Type != MeldSequence {
+			return false
+		}
+	}
+	return isRyanmenWin(d.Melds[d.WinningMeld].Tiles[0].Kind(), d.WinTile.Kind())
+}
+
+// isCompleteHand reports whether the counts split into four melds and a pair.
+func isCompleteHand(counts []int) bool {
+	for pairKind := range counts {
+		if counts[pairKind] < 2 {
+			continue
+		}
+		counts[pairKind] -= 2
+		ok := isMelds(counts, 0)
+		counts[pairKind] += 2
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isMelds(counts []int, kind int) bool {
+	for kind < tileKindNumber && counts[kind] == 0 {
+		kind++
+	}
+	if kind == tileKindNumber {
+		return true
+	}
+	if counts[kind] >= 3 {
+		counts[kind] -= 3
+		ok := isMelds(counts, kind)
+		counts[kind] += 3
+		if ok {
+			return true
+		}
+	}
+	if kind < tileKindHonorStart && kind%tileInSuitNumber <= tileInSuitNumber - 3 && counts[kind + 1] > 0 && counts[kind + 2] > 0 {
+		counts[kind]--
+		counts[kind + 1]--
+		counts[kind + 2]--
+		ok := isMelds(counts, kind)
+		counts[kind]++
+		counts[kind + 1]++
+		counts[kind + 2]++
+		return ok
+	}
+	return false
+}
+
+func isRyanmenWin(sequenceStart int, winKind int) bool {
+	rank := sequenceStart%tileInSuitNumber
+	switch winKind {
+	case sequenceStart:
+		return rank != tileInSuitNumber - 3
+	case sequenceStart + 2:
+		return rank != 0
+	}
+	return false
+}
+
+func isYakuhaiKind(kind int, playerWind Wind, roundWind Wind) bool {
+	return kind >= tileKindDragonStart || kind == windKind(playerWind) || kind == windKind(roundWind)
+}
+
+func windKind(wind Wind) int {
+	return tileKindWindStart + int(wind) - 1
+}
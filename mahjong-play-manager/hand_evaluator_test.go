@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// situationHandEvaluator records the situation of each evaluation and fails the tsumo ones
+// while failTsumo is set, leaving the rest to evaluator.
+type situationHandEvaluator struct {
+	evaluator HandEvaluator
+	failTsumo bool
+	situations []WinSituation
+	mux sync.Mutex
+}
+
+func (e *situationHandEvaluator) Evaluate(ctx context.Context, hands []Tile, winTile Tile, situation WinSituation, playerWind Wind, roundWind Wind) (*PinfuInfo, error) {
+	e.mux.Lock()
+	e.situations = append(e.situations, situation)
+	fail := e.failTsumo && situation.IsTsumo
+	e.mux.Unlock()
+	if fail {
+		return nil, errors.New("evaluation failed")
+	}
+	return e.evaluator.Evaluate(ctx, hands, winTile, situation, playerWind, roundWind)
+}
+
+func (e *situationHandEvaluator) has(match func(s WinSituation) bool) bool {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	for _, s := range e.situations {
+		if match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNewHandEvaluatorRejectsHTTPWithTsumoOrRiichi(t *testing.T) {
+	for _, rule := range []string{"tsumo", "riichi"} {
+		ruleset := testRuleset(t, yakuSetPinfu)
+		ruleset.Tsumo = rule == "tsumo"
+		ruleset.Riichi = rule == "riichi"
+		if _, err := NewHandEvaluator(&HandEvaluatorConfig{Backend: evaluatorHTTP, Ruleset: ruleset}); err == nil {
+			t.Errorf("%s: want an error for the http evaluator", rule)
+		}
+		if _, err := NewHandEvaluator(&HandEvaluatorConfig{Backend: evaluatorMock, Ruleset: ruleset}); err != nil {
+			t.Errorf("%s: %v", rule, err)
+		}
+	}
+}
+
+// The drawn tile is evaluated by the hand evaluator, and a failed evaluation pauses the table
+// until it is resumed like a failed ron evaluation.
+func TestTsumoEvaluatedByHandEvaluator(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	ruleset.Tsumo = true
+	e := &situationHandEvaluator{evaluator: &NativeHandEvaluator{ruleset}, failTsumo: true}
+	m := &MahjongPlayManager{}
+	m.Init(e, ruleset)
+	operateWant(t, m, 0, &Operator{"start", tileIdNone}, "", PhaseDealing)
+	if !m.IsPaused() {
+		t.Fatal("want the table paused by the failed tsumo evaluation")
+	}
+	e.failTsumo = false
+	operateWant(t, m, 0, &Operator{"resume", tileIdNone}, "", PhaseWaitingDiscard)
+	if m.IsPaused() {
+		t.Fatal("the table is still paused")
+	}
+
+	operateWant(t, m, m.playerIdInTurn, &Operator{"discard", tileIdNone}, "", PhaseWaitingDiscard)
+	if !e.has(func(s WinSituation) bool { return s.IsTsumo }) {
+		t.Fatal("no tsumo evaluation reached the hand evaluator")
+	}
+}
+
+// A discard to a player in riichi is evaluated again by the hand evaluator for riichi and ippatsu.
+func TestRiichiRonEvaluatedByHandEvaluator(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	ruleset.Riichi = true
+	e := &situationHandEvaluator{evaluator: &NativeHandEvaluator{ruleset}}
+	m := &MahjongPlayManager{}
+	m.Init(e, ruleset)
+	operateWant(t, m, 0, &Operator{"start", tileIdNone}, "", PhaseWaitingDiscard)
+	discarder, winner := setUpRon(t, m)
+	m.playerInfos[winner].Riichi = true
+	m.playerInfos[winner].Ippatsu = true
+	operateWant(t, m, discarder, &Operator{"discard", tileIdNone}, "", PhaseWaitingRon)
+	if !e.has(func(s WinSituation) bool { return s.IsRiichi && s.IsIppatsu }) {
+		t.Fatal("no riichi evaluation reached the hand evaluator")
+	}
+	if p := m.playerInfos[winner]; p.PinfuInfo.Han != 3 {
+		t.Fatalf("got %+v, want pinfu, riichi and ippatsu", p.PinfuInfo)
+	}
+}
@@ -2,29 +2,45 @@
 // https://github.com/gorilla/websocket/blob/master/LICENSE
 
 package main
-import "log"
+
+import (
+	"sync"
+)
 
 type Hub struct {
 	clients map[*Client]bool
-	broadcast chan []byte
+	broadcast chan [][]byte
 	register chan *Client
 	unregister chan *Client
-	mahjongPlayManager *MahjongPlayManager
+	direct chan *directMessage
+	quit chan struct{}
+	seats []*Client
+	spectators []*Client
+	started bool
+	seatMux sync.Mutex
 }
 
-func newHub(m *MahjongPlayManager) *Hub {
+func newHub() *Hub {
 	return &Hub{
-		broadcast:  make(chan []byte),
+		broadcast:  make(chan [][]byte),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		direct:     make(chan *directMessage),
+		quit:       make(chan struct{}),
+		seats:      make([]*Client, playerNumber),
 		clients:    make(map[*Client]bool),
-		mahjongPlayManager:    m,
 	}
 }
 
 func (h *Hub) run() {
 	for {
 		select {
+		case <-h.quit:
+			for client := range h.clients {
+				close(client.send)
+				delete(h.clients, client)
+			}
+			return
 		case client := <-h.register:
 			h.clients[client] = true
 		case client := <-h.unregister:
@@ -32,13 +48,24 @@ func (h *Hub) run() {
 				delete(h.clients, client)
 				close(client.send)
 			}
-		case message := <-h.broadcast:
-			var _ = message
+		case d := <-h.direct:
+			if _, ok := h.clients[d.client]; ok {
+				select {
+				case d.client.send <- d.message:
+				default:
+					close(d.client.send)
+					delete(h.clients, d.client)
+				}
+			}
+		case messages := <-h.broadcast:
 			for client := range h.clients {
-				log.Printf("client playerId:%d", client.playerId)
+				playerId := client.PlayerId()
+				if playerId == playerIdNone || messages[playerId] == nil {
+					continue
+				}
 				select {
-				case client.send <- h.mahjongPlayManager.sendMessages[client.playerId].ToBytes():
-				log.Printf("sendMessage:%s", h.mahjongPlayManager.sendMessages[client.playerId].ToBytes())
+				case client.send <- messages[playerId]:
+					logger.Debug("send message", F("playerId", playerId), Hidden("message", string(messages[playerId])))
 				default:
 					close(client.send)
 					delete(h.clients, client)
@@ -47,3 +74,27 @@ func (h *Hub) run() {
 		}
 	}
 }
+
+// Register, Unregister and Broadcast give up once the hub has quit, so that
+// clients of a destroyed room do not block. Broadcast sends each seat its own message,
+// skipping the seats without one.
+func (h *Hub) Register(client *Client) {
+	select {
+	case h.register <- client:
+	case <-h.quit:
+	}
+}
+
+func (h *Hub) Unregister(client *Client) {
+	select {
+	case h.unregister <- client:
+	case <-h.quit:
+	}
+}
+
+func (h *Hub) Broadcast(messages [][]byte) {
+	select {
+	case h.broadcast <- messages:
+	case <-h.quit:
+	}
+}
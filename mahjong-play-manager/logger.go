@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+const (
+	redacted = "[redacted]"
+)
+
+var logLevelNames = [...]string{"debug", "info", "warn", "error"}
+
+func (l LogLevel) String() string {
+	return logLevelNames[l]
+}
+
+func ParseLogLevel(name string) (LogLevel, error) {
+	for level, n := range logLevelNames {
+		if n == name {
+			return LogLevel(level), nil
+		}
+	}
+	return LogInfo, fmt.Errorf("unknown log level: %s", name)
+}
+
+// Field is a key and a value of a log line. A hidden field holds what the players must not see,
+// such as the tiles of hands or the wall, and is redacted unless the logger reveals hidden fields.
+type Field struct {
+	Key string
+	Value interface{}
+	Hidden bool
+}
+
+func F(key string, value interface{}) Field {
+	return Field{key, value, false}
+}
+
+func Hidden(key string, value interface{}) Field {
+	return Field{key, value, true}
+}
+
+// Logger writes leveled lines of key=value pairs.
+type Logger struct {
+	level LogLevel
+	revealHidden bool
+	out *log.Logger
+}
+
+// logger is the logger of the server. It is replaced only in main before anything is logged.
+var logger = NewLogger(LogInfo, false)
+
+func NewLogger(level LogLevel, revealHidden bool) *Logger {
+	return &Logger{level, revealHidden, log.New(os.Stderr, "", log.LstdFlags)}
+}
+
+func (l *Logger) Debug(msg string, fields ...Field) {
+	l.print(LogDebug, msg, fields)
+}
+
+func (l *Logger) Info(msg string, fields ...Field) {
+	l.print(LogInfo, msg, fields)
+}
+
+func (l *Logger) Warn(msg string, fields ...Field) {
+	l.print(LogWarn, msg, fields)
+}
+
+func (l *Logger) Error(msg string, fields ...Field) {
+	l.print(LogError, msg, fields)
+}
+
+func (l *Logger) Fatal(msg string, fields ...Field) {
+	l.print(LogError, msg, fields)
+	os.Exit(1)
+}
+
+func (l *Logger) print(level LogLevel, msg string, fields []Field) {
+	if level < l.level {
+		return
+	}
+	var b strings.Builder
+	b.WriteString("level=" + level.String())
+	b.WriteString(" msg=" + logValue(msg))
+	for _, f := range fields {
+		value := redacted
+		if !f.Hidden || l.revealHidden {
+			value = logValue(fmt.Sprint(f.Value))
+		}
+		b.WriteString(" " + f.Key + "=" + value)
+	}
+	l.out.Println(b.String())
+}
+
+func logValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -1,14 +1,12 @@
 package main
 
 import (
-	"log"
+	"context"
 	"sort"
-	crypto_rand "crypto/rand"
-	"encoding/binary"
+	"encoding/hex"
 	"math"
-	"math/rand"
 	"encoding/json"
-	"sync"
+	"time"
 )
 
 const (
@@ -19,25 +17,38 @@ const (
 	playerNumber = 4
 	playerIdNone = -1
 	roundNumber = 4
-	pointStart = 25000
-	costBySubRound = 300
+	umaPointUnit = 1000
+	evaluationTimeout = 10 * time.Second
 )
 
-var umaByOrder = [...]int{20, 10, -10, -20}
-
 type Wind int
 
 type MahjongPlayManager struct {
 	round *Round
-	playerNumber int
 	playerIdInTurn int
 	playerInfos []*PlayerInfo
-	mount []int
-	mountPosition int
-	waitingNext bool
-	waitingNextMux sync.Mutex
+	wall *Wall
+	phase Phase
+	ruleset *Ruleset
+	ronDecisions map[int]bool
+	ronWindow int
+	commands chan *command
+	timeouts chan int
+	resends chan int
+	quit chan struct{}
+	lastSnapshot [][]byte
+	seed []byte
+	dealtHands int
+	record *GameRecord
+	wallReveal *WallReveal
+	riichiSticks int
+	pendingRiichi int
 	isDealerWin bool
 	sendMessages []*SendMessage
+	handEvaluator HandEvaluator
+	paused bool
+	retry func()
+	discardedTile Tile
 }
 
 type PlayInfo struct {
@@ -46,6 +57,10 @@ type PlayInfo struct {
 	PlayerIds []int `json:"playerIds"`
 	Winds []Wind `json:"winds"`
 	Points []int `json:"points"`
+	WallCommitment string `json:"wallCommitment"`
+	RiichiSticks int `json:"riichiSticks"`
+	Rivers [][]Tile `json:"rivers,omitempty"`
+	CanRon bool `json:"canRon,omitempty"`
 }
 
 type PlayerInfo struct {
@@ -53,16 +68,27 @@ type PlayerInfo struct {
 	Point int `json:"-"`
 	FirstPinfuOrder int `json:"-"`
 	Wind Wind `json:"wind"`
-	Hands []int `json:"hands"`
-	DrawnTile int `json:"drawnTile"`
-	DiscardedTileUp int `json:"discardedTileUp"`
+	Hands []Tile `json:"hands"`
+	DrawnTile Tile `json:"drawnTile"`
+	DiscardedTileUp Tile `json:"discardedTileUp"`
 	PinfuInfo *PinfuInfo `json:"-"`
+	WinningTable WinningTable `json:"-"`
+	Waits []*Wait `json:"waits"`
+	River []Tile `json:"-"`
+	TsumoInfo *PinfuInfo `json:"-"`
+	CanTsumo bool `json:"canTsumo"`
+	Riichi bool `json:"riichi"`
+	Ippatsu bool `json:"-"`
+	CanRiichi bool `json:"canRiichi"`
+	TemporaryFuriten bool `json:"-"`
+	RiichiFuriten bool `json:"-"`
 }
 
 type DiscardedTileInfo struct {
 	PlayerPosition int `json:"playerPosition"`
-	DiscardedTile int `json:"discardedTile"`
+	DiscardedTile Tile `json:"discardedTile"`
 	CanRon bool `json:"canRon"`
+	Riichi bool `json:"riichi"`
 }
 
 type CanRonInfo struct {
@@ -72,11 +98,20 @@ type CanRonInfo struct {
 type RonInfo struct {
 	Point int `json:"point"`
 	PointDiff int `json:"pointDiff"`
+	WaitShape WaitShape `json:"waitShape,omitempty"`
+	Score *Score `json:"score,omitempty"`
+	Yaku []string `json:"yaku,omitempty"`
 }
 
 type DrawnRoundInfo struct {
 	RonInfo []*RonInfo `json:"ronInfo"`
 	DiscardedTileInfo *DiscardedTileInfo `json:"discardedTileInfo"`
+	WallReveal *WallReveal `json:"wallReveal"`
+}
+
+type RonRoundInfo struct {
+	RonInfo []*RonInfo `json:"ronInfo"`
+	WallReveal *WallReveal `json:"wallReveal"`
 }
 
 type Round struct {
@@ -106,17 +141,25 @@ func WindList() []Wind {
 	return []Wind{EAST, SOUTH, WEST, NORTH}
 }
 
-func (m *MahjongPlayManager) Init() {
+func (m *MahjongPlayManager) Init(handEvaluator HandEvaluator, ruleset *Ruleset) {
 	m.round = &Round{EAST, 1, 0}
-	m.playerNumber = playerIdNone
+	m.ruleset = ruleset
 	m.playerInfos = make([]*PlayerInfo, playerNumber)
 	for i := range m.playerInfos {
-		m.playerInfos[i] = &PlayerInfo{i, pointStart, playerNumber + 1, WindList()[i], make([]int, tileInHandNumber), tileIdNone, tileIdNone, &PinfuInfo{false, 0}}
+		m.playerInfos[i] = &PlayerInfo{i, ruleset.StartPoints, playerNumber + 1, WindList()[i], make([]Tile, tileInHandNumber), TileNone, TileNone, &PinfuInfo{false, 0, 0, 0, nil, ""}, WinningTable{}, []*Wait{}, []Tile{}, &PinfuInfo{false, 0, 0, 0, nil, ""}, false, false, false, false, false, false}
 	}
 	m.InitSeed()
-	m.waitingNext = false
+	m.phase = PhaseWaitingPlayers
 	m.isDealerWin = false
 	m.sendMessages = make([]*SendMessage, playerNumber)
+	m.handEvaluator = handEvaluator
+	m.ronDecisions = map[int]bool{}
+	m.riichiSticks = 0
+	m.pendingRiichi = playerIdNone
+	m.commands = make(chan *command)
+	m.timeouts = make(chan int)
+	m.resends = make(chan int)
+	m.quit = make(chan struct{})
 }
 
 func (m *MahjongPlayManager) InitPlayerIdInTrun() {
@@ -128,39 +171,84 @@ func (m *MahjongPlayManager) InitPlayerIdInTrun() {
 	}
 }
 
+// InitSeed gives the game a random secret seed.
 func (m *MahjongPlayManager) InitSeed() {
-	var b [8]byte
-	crypto_rand.Read(b[:])
-	rand.Seed(int64(binary.LittleEndian.Uint64(b[:])))
+	m.SetSeed(NewSeed())
+}
+
+// SetSeed replaces the secret seed the walls of the game are derived from. It must be set
+// before the game starts, then the same seed and the same actions play the same game.
+func (m *MahjongPlayManager) SetSeed(seed []byte) {
+	m.seed = seed
+	m.dealtHands = 0
+	m.record = &GameRecord{hex.EncodeToString(seed), []*Action{}}
+	logger.Debug("game seed", Hidden("seed", m.record.Seed))
+}
+
+func (m *MahjongPlayManager) Seed() []byte {
+	return m.seed
 }
 
 func (m *MahjongPlayManager) InitRound() {
 	m.InitPlayerInfos()
 	m.InitPlayerIdInTrun()
-	m.InitMount()
+	m.InitWall()
 	m.InitHands()
 	m.DistributeTile()
 	m.isDealerWin = false
+	m.paused = false
+	m.discardedTile = TileNone
+	m.pendingRiichi = playerIdNone
+}
+
+// StartRound deals a new round and sends it with send once the winning tables are ready.
+func (m *MahjongPlayManager) StartRound(send func()) {
+	m.setPhase(PhaseDealing)
+	m.InitRound()
+	m.ProceedStartRound(send)
+}
+
+func (m *MahjongPlayManager) ProceedStartRound(send func()) {
+	err := m.UpdateWinningTables(0, 1, 2, 3)
+	if err == nil {
+		err = m.CheckDrawnTile()
+	}
+	if err != nil {
+		m.Pause(err, func() {
+			m.ProceedStartRound(send)
+		})
+		return
+	}
+	m.paused = false
+	m.setPhase(PhaseWaitingDiscard)
+	send()
 }
 
 func (m *MahjongPlayManager) InitPlayerInfos() {
 	for _, p := range m.playerInfos {
-		p.DrawnTile = tileIdNone
-		p.DiscardedTileUp = tileIdNone
-		p.PinfuInfo = &PinfuInfo{false, 0}
+		p.DrawnTile = TileNone
+		p.DiscardedTileUp = TileNone
+		p.PinfuInfo = &PinfuInfo{false, 0, 0, 0, nil, ""}
+		p.WinningTable = WinningTable{}
+		p.River = []Tile{}
+		p.TsumoInfo = &PinfuInfo{false, 0, 0, 0, nil, ""}
+		p.CanTsumo = false
+		p.Riichi = false
+		p.Ippatsu = false
+		p.CanRiichi = false
+		p.TemporaryFuriten = false
+		p.RiichiFuriten = false
 	}
 }
 
-func (m *MahjongPlayManager) InitMount() {
-	m.mountPosition = 0
-	m.mount = make([]int, tileInMountNumber)
-	for i := range m.mount {
-		m.mount[i] = i
-	}
-	rand.Shuffle(len(m.mount), func(i, j int) {
-		m.mount[i], m.mount[j] = m.mount[j], m.mount[i]
-	})
-	log.Println(m.mount)
+// InitWall shuffles the wall of the hand from its hand seed and commits to it.
+// The commitment is sent with the start of the hand and opened when the hand is over.
+func (m *MahjongPlayManager) InitWall() {
+	seed := HandSeed(m.seed, m.dealtHands)
+	m.dealtHands++
+	m.wallReveal = NewWallReveal(seed)
+	m.wall = NewWall(ShuffleWall(seed), m.ruleset.DeadWall)
+	logger.Debug("wall", F("commitment", m.wallReveal.Commitment), Hidden("wall", FormatTiles(m.wall.Tiles())))
 }
 
 func (m *MahjongPlayManager) InitHands() {
@@ -169,34 +257,21 @@ func (m *MahjongPlayManager) InitHands() {
 		for j := 0; j < playerNumber; j++ {
 			targetId := (m.playerIdInTurn + j) % playerNumber
 			for k := 0; k < tileInDistributionClusterNumber; k++ {
-				m.playerInfos[targetId].Hands[i*tileInDistributionClusterNumber + k] = m.mount[m.mountPosition]
-				m.mountPosition++
+				m.playerInfos[targetId].Hands[i*tileInDistributionClusterNumber + k] = m.wall.Draw()
 			}
 		}
 	}
 
 	for i := 0; i < playerNumber; i++ {
 		targetId := (m.playerIdInTurn + i) % playerNumber
-		m.playerInfos[targetId].Hands[tileInHandNumber - 1] = m.mount[m.mountPosition]
-		m.mountPosition++
+		m.playerInfos[targetId].Hands[tileInHandNumber - 1] = m.wall.Draw()
 	}
 
 	for i := 0; i < playerNumber; i++ {
-		sort.Ints(m.playerInfos[i].Hands)
+		SortTiles(m.playerInfos[i].Hands)
 	}
 }
 
-func (m *MahjongPlayManager) newPlayerNumber() int {
-	m.playerNumber++
-	m.playerNumber = m.playerNumber % playerNumber
-	return m.playerNumber
-}
-
-func (m *MahjongPlayManager) isReady() bool {
-	log.Printf("playerNumber:%d", m.playerNumber)
-	return m.playerNumber == playerNumber - 1
-}
-
 func (m *MahjongPlayManager) GenerateEachPlayerIds(playerId int) []int {
 	playerIds := make([]int, playerNumber)
 	for i := range playerIds {
@@ -213,6 +288,14 @@ func (m *MahjongPlayManager) GenerateEachWinds(playerId int) []Wind {
 	return winds
 }
 
+func (m *MahjongPlayManager) GenerateEachRivers(playerId int) [][]Tile {
+	rivers := make([][]Tile, playerNumber)
+	for i, p := range m.playerInfos {
+		rivers[(playerNumber - playerId + i) % playerNumber] = p.River
+	}
+	return rivers
+}
+
 func (m *MahjongPlayManager) GenerateEachPoints(playerId int) []int {
 	points := make([]int, playerNumber)
 	for i, p := range m.playerInfos {
@@ -221,60 +304,258 @@ func (m *MahjongPlayManager) GenerateEachPoints(playerId int) []int {
 	return points
 }
 
+// HandTiles is the hands of the player with the drawn tile when the player holds one.
+func (m *MahjongPlayManager) HandTiles(playerId int) []Tile {
+	p := m.playerInfos[playerId]
+	tiles := append([]Tile{}, p.Hands...)
+	if p.DrawnTile.IsValid() {
+		tiles = append(tiles, p.DrawnTile)
+	}
+	return tiles
+}
+
+// VisibleTiles is every tile a seat can see outside its own hands, which is all the rivers
+// and so the same for every seat.
+func (m *MahjongPlayManager) VisibleTiles() []Tile {
+	tiles := []Tile{}
+	for _, p := range m.playerInfos {
+		tiles = append(tiles, p.River...)
+	}
+	return tiles
+}
+
+func (m *MahjongPlayManager) Shanten(playerId int) int {
+	return Shanten(m.HandTiles(playerId))
+}
+
+// Ukeire is the ukeire of the hands of a player waiting for a draw.
+func (m *MahjongPlayManager) Ukeire(playerId int) ([]*UkeireTile, error) {
+	return Ukeire(m.playerInfos[playerId].Hands, m.VisibleTiles())
+}
+
+// DiscardCandidates is the ukeire after each discard for the player holding a drawn tile.
+func (m *MahjongPlayManager) DiscardCandidates(playerId int) ([]*DiscardCandidate, error) {
+	return DiscardCandidates(m.HandTiles(playerId), m.VisibleTiles())
+}
+
 func (m *MahjongPlayManager) DistributeTile() {
-	m.playerInfos[m.playerIdInTurn].DrawnTile = m.mount[m.mountPosition]
-	m.mountPosition++
+	p := m.playerInfos[m.playerIdInTurn]
+	p.DrawnTile = m.wall.Draw()
+}
+
+// ProceedDraw checks the tile just drawn by the player in turn and sends the draw with send.
+// The table is paused instead when the hands could not be evaluated.
+func (m *MahjongPlayManager) ProceedDraw(send func()) {
+	if err := m.CheckDrawnTile(); err != nil {
+		m.Pause(err, func() {
+			m.ProceedDraw(send)
+		})
+		return
+	}
+	m.paused = false
+	m.setPhase(PhaseWaitingDiscard)
+	send()
+}
+
+// CheckDrawnTile tells the player in turn whether the drawn tile wins and whether riichi can be declared.
+func (m *MahjongPlayManager) CheckDrawnTile() error {
+	p := m.playerInfos[m.playerIdInTurn]
+	if err := m.CheckTsumo(p); err != nil {
+		return err
+	}
+	m.CheckRiichi(p)
+	return nil
+}
+
+// CheckTsumo evaluates the drawn tile of the player when the ruleset allows tsumo.
+// The winning tables are only for discards, so the hands are evaluated here.
+func (m *MahjongPlayManager) CheckTsumo(p *PlayerInfo) error {
+	p.TsumoInfo = &PinfuInfo{false, 0, 0, 0, nil, ""}
+	p.CanTsumo = false
+	if !m.ruleset.Tsumo {
+		return nil
+	}
+	tsumoInfo, err := m.evaluateWin(p, p.DrawnTile, WinSituation{true, m.wall.IsExhausted(), p.Riichi, p.Ippatsu})
+	if err != nil {
+		return err
+	}
+	p.TsumoInfo = tsumoInfo
+	p.CanTsumo = p.TsumoInfo.CanWin()
+	return nil
+}
+
+// evaluateWin evaluates a win of the player on a tile with the hand evaluator.
+func (m *MahjongPlayManager) evaluateWin(p *PlayerInfo, winTile Tile, situation WinSituation) (*PinfuInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), evaluationTimeout)
+	defer cancel()
+	return m.handEvaluator.Evaluate(ctx, p.Hands, winTile, situation, p.Wind, m.round.Wind)
 }
 
 func (m *MahjongPlayManager) CanDistributeTile() bool {
-	return m.mountPosition < tileInMountNumber
+	return m.wall.CanDraw()
 }
 
-func (m *MahjongPlayManager) DiscardTile(position int) int {
+func (m *MahjongPlayManager) DiscardTile(position int) Tile {
 	playerInTurn := m.playerInfos[m.playerIdInTurn]
 	discardedTile := playerInTurn.DrawnTile
 	if position >= 0 && position < len(playerInTurn.Hands) {
-		log.Printf("discard position:%d", position)
+		logger.Debug("discard position", F("position", position))
 		discardedTile = playerInTurn.Hands[position]
 		playerInTurn.Hands[position] = playerInTurn.DrawnTile
-		sort.Ints(playerInTurn.Hands)
+		SortTiles(playerInTurn.Hands)
 	}
-	log.Printf("discard drawnTile:%d", playerInTurn.DrawnTile)
-	log.Printf("discardedTile:%d", discardedTile)
-	log.Println(playerInTurn.Hands)
-	playerInTurn.DrawnTile = tileIdNone
+	logger.Debug("discard", F("playerId", m.playerIdInTurn), F("tile", discardedTile), Hidden("drawnTile", playerInTurn.DrawnTile), Hidden("hands", FormatTiles(playerInTurn.Hands)))
+	playerInTurn.DrawnTile = TileNone
+	playerInTurn.TsumoInfo = &PinfuInfo{false, 0, 0, 0, nil, ""}
+	playerInTurn.CanTsumo = false
+	playerInTurn.Ippatsu = false
+	playerInTurn.CanRiichi = false
+	playerInTurn.TemporaryFuriten = false
+	playerInTurn.River = append(playerInTurn.River, discardedTile)
+	m.discardedTile = discardedTile
 	return discardedTile
 }
 
-func (m *MahjongPlayManager) CheckPinfuAndSetRon(discardedTile int) bool {
+// ProceedDiscard checks ron against the discarded tile and moves the turn on.
+// The table is paused instead when the hands could not be evaluated.
+func (m *MahjongPlayManager) ProceedDiscard(discardedTile Tile) {
+	err := m.UpdateWinningTables(m.playerIdInTurn)
+	canRon := false
+	if err == nil {
+		canRon, err = m.CheckPinfuAndSetRon(discardedTile)
+	}
+	if err != nil {
+		m.Pause(err, func() {
+			m.ProceedDiscard(discardedTile)
+		})
+		return
+	}
+	m.paused = false
+	if canRon {
+		m.OpenRonWindow()
+		m.SendMessageDiscard(m.playerIdInTurn)
+		m.SendMessageDiscardOther(m.playerIdInTurn, discardedTile)
+	} else {
+		m.acceptRiichi()
+		m.markPassedDiscard()
+		if m.CanDistributeTile() {
+			playerIdInTurnBefore := m.RotatePlayer()
+			m.DistributeTile()
+			m.ProceedDraw(func() {
+				m.SendMessageDiscard(playerIdInTurnBefore)
+				m.SendMessageDiscardOther(playerIdInTurnBefore, discardedTile)
+				m.SendMessageDrawn(discardedTile)
+			})
+		} else {
+			m.setPhase(PhaseHandOver)
+
+			m.SendMessageDrawnRound(discardedTile)
+		}
+	}
+}
+
+func (m *MahjongPlayManager) CheckPinfuAndSetRon(discardedTile Tile) (bool, error) {
 	canRon := false
 	for i, p := range m.playerInfos {
 		if i != m.playerIdInTurn {
-			p.PinfuInfo = m.PinfuQuery(p.Hands, discardedTile, int(m.round.Wind), int(p.Wind))
-			if p.PinfuInfo.IsPinfu {
+			pinfuInfo, err := m.evaluateRon(p, discardedTile)
+			if err != nil {
+				return false, err
+			}
+			p.PinfuInfo = pinfuInfo
+			if m.IsFuriten(p) {
+				p.PinfuInfo = &PinfuInfo{false, 0, 0, 0, nil, ""}
+			}
+			if p.PinfuInfo.CanWin() {
 				canRon = true
 			}
-			log.Printf("canRon:%d", m.playerIdInTurn)
+			logger.Debug("ron check", F("playerId", i), Hidden("canWin", p.PinfuInfo.CanWin()))
+		}
+	}
+	return canRon, nil
+}
+
+// evaluateRon is what the player wins with the discard. The winning tables do not know
+// the wall or riichi, so a discard after the last live tile is evaluated again for houtei
+// and a discard to a player in riichi for riichi and ippatsu.
+func (m *MahjongPlayManager) evaluateRon(p *PlayerInfo, discardedTile Tile) (*PinfuInfo, error) {
+	isHoutei := m.wall.IsExhausted() && m.ruleset.HasYaku(yakuHoutei)
+	if !isHoutei && !p.Riichi {
+		return p.WinningTable.Lookup(discardedTile), nil
+	}
+	for _, kind := range winningKinds(p.Hands) {
+		if kind == discardedTile.Kind() {
+			return m.evaluateWin(p, discardedTile, WinSituation{false, m.wall.IsExhausted(), p.Riichi, p.Ippatsu})
 		}
 	}
-	return canRon
+	return &PinfuInfo{false, 0, 0, 0, nil, ""}, nil
+}
+
+// Pause stops the table until a player resumes it, which runs retry.
+func (m *MahjongPlayManager) Pause(err error, retry func()) {
+	logger.Error("evaluation failed", F("error", err))
+	m.paused = true
+	m.retry = retry
+	m.SendMessagePause()
+}
+
+func (m *MahjongPlayManager) Resume() {
+	m.retry()
 }
 
-func (m *MahjongPlayManager) PinfuQuery(hands []int, discardedTile, wind int, selfWind int) *PinfuInfo {
-	p := PinfuQuery{}
-	p.Parse(hands, discardedTile, selfWind, wind)
-	log.Println(p)
-	return p.Query()
+func (m *MahjongPlayManager) IsPaused() bool {
+	return m.paused
 }
 
-func (m *MahjongPlayManager) CalculateRonInfo(playerId int) []*RonInfo {
+// CalculateRonInfo pays each winner from the discarder. The honba and the riichi deposits go to the first winner only.
+func (m *MahjongPlayManager) CalculateRonInfo(playerIds ...int) []*RonInfo {
 	r := make([]*RonInfo, playerNumber)
 	for i, p := range m.playerInfos {
-		r[i] = &RonInfo{p.Point, 0}
+		r[i] = &RonInfo{p.Point, 0, "", nil, nil}
 	}
-	cost := m.playerInfos[playerId].PinfuInfo.Cost + costBySubRound*m.round.SubRound
-	r[playerId].Update(cost)
-	r[m.playerIdInTurn].Update(-cost)
+	for i, playerId := range playerIds {
+		p := m.playerInfos[playerId]
+		honbaPoints := 0
+		if i == 0 {
+			honbaPoints = m.ruleset.HonbaPoints * m.round.SubRound
+			r[playerId].Update(m.riichiSticksPoints())
+		}
+		score := NewScore(p.PinfuInfo.Han, p.PinfuInfo.Fu)
+		cost := score.RonPayment(p.Wind == EAST, honbaPoints)
+		r[playerId].Update(cost)
+		r[playerId].WaitShape = p.PinfuInfo.WaitShape
+		r[playerId].Score = score
+		r[playerId].Yaku = p.PinfuInfo.Yaku
+		r[m.playerIdInTurn].Update(-cost)
+	}
+	return r
+}
+
+// CalculateTsumoInfo pays the player in turn from every other seat, the dealer paying the dealer share,
+// and gives the riichi deposits to the player.
+func (m *MahjongPlayManager) CalculateTsumoInfo(playerId int) []*RonInfo {
+	r := make([]*RonInfo, playerNumber)
+	for i, p := range m.playerInfos {
+		r[i] = &RonInfo{p.Point, 0, "", nil, nil}
+	}
+	p := m.playerInfos[playerId]
+	score := NewScore(p.TsumoInfo.Han, p.TsumoInfo.Fu)
+	dealerPayment, nonDealerPayment := score.TsumoPayments(p.Wind == EAST, m.ruleset.HonbaPoints * m.round.SubRound)
+	for i, other := range m.playerInfos {
+		if i == playerId {
+			continue
+		}
+		payment := nonDealerPayment
+		if other.Wind == EAST {
+			payment = dealerPayment
+		}
+		r[i].Update(-payment)
+		r[playerId].Update(payment)
+	}
+	r[playerId].Update(m.riichiSticksPoints())
+	r[playerId].WaitShape = p.TsumoInfo.WaitShape
+	r[playerId].Score = score
+	r[playerId].Yaku = p.TsumoInfo.Yaku
 	return r
 }
 
@@ -308,24 +589,23 @@ func (m *MahjongPlayManager) CalculateResult() []*Result {
 		for i, p := range m.playerInfos {
 			points[i].playerId = i
 			points[i].point = p.Point + (10 - p.FirstPinfuOrder) * 10 - p.PlayerId
-			log.Println(points[i].point)
 		}
 		sort.SliceStable(points, func(i, j int) bool {
 			return points[i].point > points[j].point
 		})
 		for i := range m.playerInfos {
-			points[i].point = int(math.Floor(float64((points[i].point + 400)/1000))) - 30
+			points[i].point = int(math.Floor(float64((points[i].point + 400)/umaPointUnit))) - m.ruleset.ReturnPoints/umaPointUnit
 		}
 		for i := 0; i < playerNumber; i++ {
 			points[0].point -= points[i].point
 		}
 
 		for i, p := range points {
-			r[p.playerId] = &Result{p.point + umaByOrder[i], i + 1}
+			r[p.playerId] = &Result{p.point + m.ruleset.Uma[i], i + 1}
 		}
 	} else {
 		for i := range points {
-			r[i] = &Result{int(math.Floor(float64((points[i].point + 400)/1000))), 0}
+			r[i] = &Result{int(math.Floor(float64((points[i].point + 400)/umaPointUnit))), 0}
 		}
 	}
 	return r
@@ -340,29 +620,24 @@ func (m *MahjongPlayManager) SendMessagePlay(messageType string) {
 		playerIds := m.GenerateEachPlayerIds(i)
 		winds := m.GenerateEachWinds(i)
 		points := m.GenerateEachPoints(i)
-		m.sendMessages[i] = &SendMessage{messageType, &PlayInfo{m.round, m.playerInfos[i], playerIds, winds, points}}
+		m.sendMessages[i] = &SendMessage{messageType, &PlayInfo{m.round, m.playerInfos[i], playerIds, winds, points, m.wallReveal.Commitment, m.riichiSticks, nil, false}}
 	}
 }
 
 func (m *MahjongPlayManager) SendMessageDiscard(playerIdInTurnBefore int) {
-	log.Printf("send message discard playerId:%d", playerIdInTurnBefore)
-	for _, v := range m.playerInfos {
-		log.Println(v)
-	}
 	m.sendMessages[playerIdInTurnBefore] = &SendMessage{"discard", &m.playerInfos[playerIdInTurnBefore]}
 }
 
-func (m *MahjongPlayManager) SendMessageDrawn(discardedTile int) {
+func (m *MahjongPlayManager) SendMessageDrawn(discardedTile Tile) {
 	m.playerInfos[m.playerIdInTurn].DiscardedTileUp = discardedTile
 	m.sendMessages[m.playerIdInTurn] = &SendMessage{"drawn", &m.playerInfos[m.playerIdInTurn]}
 }
 
-func (m *MahjongPlayManager) SendMessageDiscardOther(playerIdInTurnBefore int, discardedTile int) {
-	log.Printf("discardedTile:%d", discardedTile)
+func (m *MahjongPlayManager) SendMessageDiscardOther(playerIdInTurnBefore int, discardedTile Tile) {
 	playerIds := m.GenerateEachPlayerIds(playerIdInTurnBefore)
 	for i := range m.sendMessages {
 		if i != playerIdInTurnBefore {
-			r := &DiscardedTileInfo{playerIds[i], discardedTile, m.playerInfos[i].PinfuInfo.IsPinfu}
+			r := &DiscardedTileInfo{playerIds[i], discardedTile, m.playerInfos[i].PinfuInfo.CanWin(), m.playerInfos[playerIdInTurnBefore].Riichi}
 			m.sendMessages[i] = &SendMessage{"discardOther", r}
 		}
 	}
@@ -371,14 +646,26 @@ func (m *MahjongPlayManager) SendMessageDiscardOther(playerIdInTurnBefore int, d
 func (m *MahjongPlayManager) SendMessageCanRon() {
 	for i, p := range m.playerInfos {
 		if i != m.playerIdInTurn {
-			m.sendMessages[i] = &SendMessage{"canRon", CanRonInfo{p.PinfuInfo.IsPinfu}}
+			m.sendMessages[i] = &SendMessage{"canRon", CanRonInfo{p.PinfuInfo.CanWin()}}
 		}
 	}
 }
 
+func (m *MahjongPlayManager) SendMessagePause() {
+	for i := range m.sendMessages {
+		m.sendMessages[i] = &SendMessage{"pause", ""}
+	}
+}
+
 func (m *MahjongPlayManager) SendMessageRon(r []*RonInfo) {
 	for i := range m.sendMessages {
-		m.sendMessages[i] = &SendMessage{"ron", r}
+		m.sendMessages[i] = &SendMessage{"ron", &RonRoundInfo{r, m.wallReveal}}
+	}
+}
+
+func (m *MahjongPlayManager) SendMessageTsumo(r []*RonInfo) {
+	for i := range m.sendMessages {
+		m.sendMessages[i] = &SendMessage{"tsumo", &RonRoundInfo{r, m.wallReveal}}
 	}
 }
 
@@ -390,19 +677,17 @@ func (m *MahjongPlayManager) SendMessageSkip() {
 	}
 }
 
-func (m *MahjongPlayManager) SendMessageDrawnRound(discardedTile int) {
+func (m *MahjongPlayManager) SendMessageDrawnRound(discardedTile Tile) {
 	r := make([]*RonInfo, playerNumber)
 	for i, p := range m.playerInfos {
-		r[i] = &RonInfo{p.Point, 0}
+		r[i] = &RonInfo{p.Point, 0, "", nil, nil}
 	}
 	for i := range m.sendMessages {
-		m.sendMessages[i] = &SendMessage{"drawnRound", &DrawnRoundInfo{r, &DiscardedTileInfo{(playerNumber - m.playerIdInTurn + i) % playerNumber, discardedTile, false}}}
-	log.Printf("DiscardedTileInfo:%d", (playerNumber - m.playerIdInTurn + i) % playerNumber)
+		m.sendMessages[i] = &SendMessage{"drawnRound", &DrawnRoundInfo{r, &DiscardedTileInfo{(playerNumber - m.playerIdInTurn + i) % playerNumber, discardedTile, false, m.playerInfos[m.playerIdInTurn].Riichi}, m.wallReveal}}
 	}
 }
 
 func (m *MahjongPlayManager) SendMessageNext() {
-	log.Println("SendMessageNext")
 	m.SendMessagePlay("next")
 }
 
@@ -412,33 +697,23 @@ func (m *MahjongPlayManager) SendMessageResult(r []*Result) {
 	}
 }
 
-func (m *MahjongPlayManager) WaitNextMessage() {
-	m.waitingNext = true
-}
-
-func (m *MahjongPlayManager) TriggerNextMessage(f func()) bool {
-	m.waitingNextMux.Lock()
-	defer m.waitingNextMux.Unlock()
-	if m.waitingNext {
-		f()
-		m.waitingNext = false
-		return true
-	}
-	return false
-}
-
 func (m *MahjongPlayManager) IsDealerWin() bool {
 	return m.isDealerWin
 }
 
-func (m *MahjongPlayManager) DealerWin(playerId int) {
-	m.isDealerWin = m.playerInfos[playerId].Wind == EAST
+func (m *MahjongPlayManager) DealerWin(playerIds ...int) {
+	m.isDealerWin = false
+	for _, playerId := range playerIds {
+		if m.playerInfos[playerId].Wind == EAST {
+			m.isDealerWin = true
+		}
+	}
 }
 
 func (m *MahjongPlayManager) RotatePlayer() int {
 	playerIdInTurnBefore := m.playerIdInTurn
 	m.playerIdInTurn = (m.playerIdInTurn + 1) % playerNumber
-	log.Printf("playerId in turn:%d", m.playerIdInTurn)
+	logger.Debug("turn", F("playerId", m.playerIdInTurn))
 	return playerIdInTurnBefore
 }
 
@@ -459,14 +734,31 @@ func (m *MahjongPlayManager) ResetSubRound() {
 func (m *MahjongPlayManager) RotateRound() {
 	if !m.round.IsFinalRound() {
 		m.round.Round++
-	} else if !m.round.IsFinalWind() {
+	} else {
 		m.round.Wind = m.round.Wind.Next()
 		m.round.Round = 1
 	}
 }
 
+// continueGame tells whether another hand is dealt after this one by the game length
+// and end conditions of the ruleset.
 func (m *MahjongPlayManager) continueGame() bool {
-	return !m.round.IsFinalRound() || (m.isDrawnGame() && !m.round.IsFinalWind())
+	if m.ruleset.EndOnBust && m.hasBustPlayer() {
+		return false
+	}
+	if !m.round.IsFinalRound() || m.round.Wind < m.ruleset.FinalWind() {
+		return true
+	}
+	return m.ruleset.ExtendOnTie && m.round.Wind == m.ruleset.FinalWind() && m.isDrawnGame()
+}
+
+func (m *MahjongPlayManager) hasBustPlayer() bool {
+	for _, p := range m.playerInfos {
+		if p.Point < 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func (m *MahjongPlayManager) isDrawnGame() bool {
@@ -502,13 +794,9 @@ func (r *Round) IsFinalRound() bool {
 	return r.Round == roundNumber
 }
 
-func (r *Round) IsFinalWind() bool {
-	return r.Wind == SOUTH
-}
-
 func (r *RonInfo) Update(cost int) {
 	r.Point = r.Point + cost
-	r.PointDiff = cost
+	r.PointDiff += cost
 }
 
 func (s *SendMessage) ToBytes() []byte {
@@ -518,6 +806,6 @@ func (s *SendMessage) ToBytes() []byte {
 /*
 func main() {
     m := MahjongPlayManager{}
-    m.Init()
+    m.Init(&NativeHandEvaluator{}, &Ruleset{})
 }
 */
\ No newline at end of file
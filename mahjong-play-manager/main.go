@@ -5,14 +5,35 @@ package main
 
 import (
 	"flag"
-	"log"
 	"net/http"
+	"time"
 )
 
 var addr = flag.String("addr", ":8080", "http service address")
+var evaluator = flag.String("evaluator", evaluatorNative, "hand evaluator backend (native, http or mock)")
+var calculatorURL = flag.String("calculator-url", "http://host.docker.internal:8000", "hands-calculation API address for the http evaluator")
+var mockScript = flag.String("mock-script", "", "JSON file of scripted results for the mock evaluator")
+var calculatorTimeout = flag.Duration("calculator-timeout", 2*time.Second, "deadline of each hands-calculation API request")
+var calculatorRetries = flag.Int("calculator-retries", 2, "retries after a failed hands-calculation API request")
+var calculatorBackoff = flag.Duration("calculator-backoff", 200*time.Millisecond, "wait before the first retry, growing with each retry")
+var breakerThreshold = flag.Int("breaker-threshold", 3, "consecutive failed evaluations that open the circuit breaker")
+var breakerCooldown = flag.Duration("breaker-cooldown", 30*time.Second, "time the circuit breaker stays open before a trial request")
+var yakuSet = flag.String("yaku", yakuSetPinfu, "yaku that can win (pinfu or any)")
+var multipleRon = flag.Bool("multiple-ron", false, "let every player who claims a discard win it instead of only the nearest one")
+var tsumo = flag.Bool("tsumo", false, "let the player in turn win on the drawn tile")
+var riichi = flag.Bool("riichi", false, "let players with tenpai hands declare riichi")
+var furiten = flag.Bool("furiten", false, "keep players who discarded or passed a winning tile from winning by ron")
+var seed = flag.String("seed", "", "hex of the 32-byte seed of the walls of every room, empty for a random seed per game")
+var replay = flag.String("replay", "", "JSON file of a game record to play again and print the result of")
+var verify = flag.String("verify", "", "JSON file of a wall reveal from the end of a hand to check against its commitment")
+var logLevel = flag.String("log-level", "info", "lowest level of logged lines (debug, info, warn or error)")
+var logHidden = flag.Bool("log-hidden", false, "log hidden information such as walls and hands, only for debugging")
+var ronTimeout = flag.Duration("ron-timeout", defaultRonTimeout, "time players who can win a discard have to answer, 0 for no limit")
+var rulesFile = flag.String("rules", "", "JSON file of the ruleset of every room, instead of -yaku, -multiple-ron, -tsumo, -riichi, -furiten and -ron-timeout")
+var rulesDir = flag.String("rules-dir", "", "directory of JSON rule files a new room can name with the rules query parameter")
 
 func serveHome(w http.ResponseWriter, r *http.Request) {
-	log.Println(r.URL)
+	logger.Debug("request", F("url", r.URL))
 	if r.URL.Path != "/" {
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
@@ -26,17 +47,104 @@ func serveHome(w http.ResponseWriter, r *http.Request) {
 
 func main() {
 	flag.Parse()
-	m := MahjongPlayManager{}
-	m.Init()
-	hub := newHub(&m)
-	go hub.run()
+	level, err := ParseLogLevel(*logLevel)
+	if err != nil {
+		logger.Fatal("ParseLogLevel", F("error", err))
+	}
+	logger = NewLogger(level, *logHidden)
+	if *verify != "" {
+		verifyWall()
+		return
+	}
+	ruleset := loadRuleset()
+	config := &HandEvaluatorConfig{
+		Backend: *evaluator,
+		CalculatorURL: *calculatorURL,
+		MockScript: *mockScript,
+		Timeout: *calculatorTimeout,
+		Retries: *calculatorRetries,
+		Backoff: *calculatorBackoff,
+		BreakerThreshold: *breakerThreshold,
+		BreakerCooldown: *breakerCooldown,
+		Ruleset: ruleset,
+	}
+	if _, err := NewHandEvaluator(config); err != nil {
+		logger.Fatal("NewHandEvaluator", F("error", err))
+	}
+	logger.Info("server started", F("evaluator", *evaluator), F("yaku", ruleset.Yaku))
+	if *replay != "" {
+		replayGame(config)
+		return
+	}
+	var roomSeed []byte
+	if *seed != "" {
+		roomSeed, err = ParseSeed(*seed)
+		if err != nil {
+			logger.Fatal("ParseSeed", F("error", err))
+		}
+	}
+	rooms := NewRoomManager(config, roomSeed, *rulesDir)
 	http.HandleFunc("/", serveHome)
 	http.Handle("/mahjong-ui/", http.StripPrefix("/mahjong-ui/", http.FileServer(http.Dir("../mahjong-ui"))))
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		serveWs(hub, w, r)
+		serveWs(rooms, w, r)
 	})
-	err := http.ListenAndServe(*addr, nil)
+	err = http.ListenAndServe(*addr, nil)
+	if err != nil {
+		logger.Fatal("ListenAndServe", F("error", err))
+	}
+}
+
+func loadRuleset() *Ruleset {
+	if *rulesFile != "" {
+		ruleset, err := LoadRuleset(*rulesFile)
+		if err != nil {
+			logger.Fatal("LoadRuleset", F("error", err))
+		}
+		return ruleset
+	}
+	ruleset, err := NewRuleset(*yakuSet)
+	if err != nil {
+		logger.Fatal("NewRuleset", F("error", err))
+	}
+	ruleset.MultipleRon = *multipleRon
+	ruleset.Tsumo = *tsumo
+	ruleset.Riichi = *riichi
+	ruleset.Furiten = *furiten
+	ruleset.RonTimeout = *ronTimeout
+	if err := ruleset.Validate(); err != nil {
+		logger.Fatal("Ruleset", F("error", err))
+	}
+	return ruleset
+}
+
+func replayGame(config *HandEvaluatorConfig) {
+	record, err := LoadGameRecord(*replay)
+	if err != nil {
+		logger.Fatal("LoadGameRecord", F("error", err))
+	}
+	handEvaluator, err := NewHandEvaluator(config)
+	if err != nil {
+		logger.Fatal("NewHandEvaluator", F("error", err))
+	}
+	m, err := Replay(record, handEvaluator, config.Ruleset)
+	if err != nil {
+		logger.Fatal("Replay", F("error", err))
+	}
+	logger.Info("replayed", F("phase", m.Phase()))
+	for _, p := range m.playerInfos {
+		logger.Info("player", F("playerId", p.PlayerId), F("point", p.Point))
+	}
+}
+
+func verifyWall() {
+	reveal, err := LoadWallReveal(*verify)
+	if err != nil {
+		logger.Fatal("LoadWallReveal", F("error", err))
+	}
+	wall, err := reveal.Verify()
 	if err != nil {
-		log.Fatal("ListenAndServe: ", err)
+		logger.Fatal("Verify", F("error", err))
 	}
+	logger.Info("verified wall", F("wall", FormatTiles(wall)))
 }
@@ -0,0 +1,164 @@
+package main
+
+// Phase is where the game is. A paused table stays in the phase it was paused in.
+type Phase int
+
+const (
+	PhaseWaitingPlayers Phase = iota
+	PhaseDealing
+	PhaseWaitingDiscard
+	PhaseWaitingRon
+	PhaseHandOver
+	PhaseGameOver
+)
+
+var phaseNames = [...]string{"waitingPlayers", "dealing", "waitingDiscard", "waitingRon", "handOver", "gameOver"}
+
+// phaseTransitions lists the phases each phase can move to.
+// A discard that nobody can win moves the turn on and waits for the next discard.
+var phaseTransitions = map[Phase][]Phase{
+	PhaseWaitingPlayers: {PhaseDealing},
+	PhaseDealing: {PhaseWaitingDiscard},
+	PhaseWaitingDiscard: {PhaseWaitingDiscard, PhaseWaitingRon, PhaseHandOver},
+	PhaseWaitingRon: {PhaseWaitingDiscard, PhaseHandOver},
+	PhaseHandOver: {PhaseDealing, PhaseGameOver},
+	PhaseGameOver: {},
+}
+
+func (p Phase) String() string {
+	return phaseNames[p]
+}
+
+func (p Phase) CanTransitionTo(next Phase) bool {
+	for _, n := range phaseTransitions[p] {
+		if n == next {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MahjongPlayManager) Phase() Phase {
+	return m.phase
+}
+
+func (m *MahjongPlayManager) setPhase(next Phase) {
+	if !m.phase.CanTransitionTo(next) {
+		logger.Warn("unexpected phase transition", F("from", m.phase), F("to", next))
+	}
+	logger.Debug("phase", F("from", m.phase), F("to", next))
+	m.phase = next
+}
+
+// Operate validates an operation of a player and applies it, leaving the outbound messages to broadcast.
+// It must only be called by the goroutine that owns the game, see Run.
+func (m *MahjongPlayManager) Operate(playerId int, o *Operator) *OperationError {
+	if err := m.ValidateOperation(playerId, o); err != nil {
+		return err
+	}
+	if !o.isResume() {
+		m.recordAction(playerId, o.Operation, o.Target)
+	}
+	switch {
+	case o.isStart():
+		m.Start()
+	case o.isDiscard():
+		m.Discard(o.Target)
+	case o.isTsumo():
+		m.Tsumo()
+	case o.isRiichi():
+		m.Riichi(o.Target)
+	case o.isResume():
+		m.Resume()
+	case o.isRon(), o.isSkip():
+		m.Decide(playerId, o.isRon())
+	case o.isNext():
+		m.Next()
+	case o.isResult():
+		m.FinishGame()
+	}
+	return nil
+}
+
+// Start deals the first round.
+func (m *MahjongPlayManager) Start() {
+	m.StartRound(m.SendMessageStart)
+}
+
+// Discard discards a tile of the player in turn, the drawn tile when position is not in the hands.
+func (m *MahjongPlayManager) Discard(position int) {
+	m.ProceedDiscard(m.DiscardTile(position))
+}
+
+// Ron is the win of players on the last discard, in seat order after the discarder, which ends the hand.
+func (m *MahjongPlayManager) Ron(playerIds ...int) {
+	m.pendingRiichi = playerIdNone
+	ronInfo := m.CalculateRonInfo(playerIds...)
+	m.UpdatePlayersPoint(ronInfo)
+	m.riichiSticks = 0
+	for _, playerId := range playerIds {
+		if m.playerInfos[playerId].PinfuInfo.IsPinfu {
+			m.SetFirstPinfuOrder(playerId)
+		}
+	}
+	m.DealerWin(playerIds...)
+	m.setPhase(PhaseHandOver)
+
+	m.SendMessageRon(ronInfo)
+}
+
+// Tsumo is the win of the player in turn on the drawn tile, paid by every other seat, which ends the hand.
+func (m *MahjongPlayManager) Tsumo() {
+	ronInfo := m.CalculateTsumoInfo(m.playerIdInTurn)
+	m.UpdatePlayersPoint(ronInfo)
+	m.riichiSticks = 0
+	if m.playerInfos[m.playerIdInTurn].TsumoInfo.IsPinfu {
+		m.SetFirstPinfuOrder(m.playerIdInTurn)
+	}
+	m.DealerWin(m.playerIdInTurn)
+	m.setPhase(PhaseHandOver)
+
+	m.SendMessageTsumo(ronInfo)
+}
+
+// Skip passes on the last discard and moves the turn on, or ends the hand when the wall is empty.
+func (m *MahjongPlayManager) Skip() {
+	m.acceptRiichi()
+	m.markPassedDiscard()
+	m.RotatePlayer()
+	if m.CanDistributeTile() {
+		m.DistributeTile()
+		m.ProceedDraw(func() {
+			m.SendMessageSkip()
+			m.SendMessageDrawn(TileNone)
+		})
+	} else {
+		m.setPhase(PhaseHandOver)
+
+		m.SendMessageDrawnRound(TileNone)
+	}
+}
+
+// Next deals the next round after a hand, or ends the game after the final round.
+func (m *MahjongPlayManager) Next() {
+	if !m.continueGame() {
+		m.FinishGame()
+		return
+	}
+	if m.IsDealerWin() {
+		m.NextSubRound()
+	} else {
+		m.RotateRound()
+		m.RotatePlayerWind()
+		m.ResetSubRound()
+	}
+	m.StartRound(m.SendMessageNext)
+}
+
+func (m *MahjongPlayManager) FinishGame() {
+	result := m.CalculateResult()
+	m.setPhase(PhaseGameOver)
+	logger.Info("game record", F("record", string(m.record.ToBytes())))
+
+	m.SendMessageResult(result)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPhaseTransitionTable(t *testing.T) {
+	allowed := map[Phase][]Phase{
+		PhaseWaitingPlayers: {PhaseDealing},
+		PhaseDealing: {PhaseWaitingDiscard},
+		PhaseWaitingDiscard: {PhaseWaitingDiscard, PhaseWaitingRon, PhaseHandOver},
+		PhaseWaitingRon: {PhaseWaitingDiscard, PhaseHandOver},
+		PhaseHandOver: {PhaseDealing, PhaseGameOver},
+	}
+	for from := PhaseWaitingPlayers; from <= PhaseGameOver; from++ {
+		for to := PhaseWaitingPlayers; to <= PhaseGameOver; to++ {
+			want := false
+			for _, p := range allowed[from] {
+				want = want || p == to
+			}
+			if got := from.CanTransitionTo(to); got != want {
+				t.Errorf("%s -> %s: got %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func newPhaseTestManager(t *testing.T) *MahjongPlayManager {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	m := &MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	return m
+}
+
+func operateWant(t *testing.T, m *MahjongPlayManager, playerId int, o *Operator, code string, phase Phase) {
+	t.Helper()
+	err := m.Operate(playerId, o)
+	switch {
+	case code == "" && err != nil:
+		t.Fatalf("%s by %d in %s: %v", o.Operation, playerId, m.Phase(), err)
+	case code != "" && (err == nil || err.Code != code):
+		t.Fatalf("%s by %d: got %v, want %s", o.Operation, playerId, err, code)
+	}
+	if m.Phase() != phase {
+		t.Fatalf("%s by %d: got phase %s, want %s", o.Operation, playerId, m.Phase(), phase)
+	}
+}
+
+// setUpRon makes the next seat after the player in turn wait on the drawn tile of the player in turn,
+// leaving the other seats without a wait. It returns the discarder and the winner.
+func setUpRon(t *testing.T, m *MahjongPlayManager) (int, int) {
+	discarder := m.playerIdInTurn
+	winner := (discarder + 1) % playerNumber
+	for i, p := range m.playerInfos {
+		if i != discarder {
+			p.Hands = mustParseTiles(t, "1479m258p369s1234z")
+		}
+	}
+	m.playerInfos[winner].Hands = mustParseTiles(t, "23m456789p123s55s")
+	m.playerInfos[discarder].DrawnTile = mustParseTiles(t, "1m")[0]
+	if err := m.UpdateWinningTables(0, 1, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	return discarder, winner
+}
+
+func TestPhasesThroughAGame(t *testing.T) {
+	m := newPhaseTestManager(t)
+	if m.Phase() != PhaseWaitingPlayers {
+		t.Fatalf("got %s", m.Phase())
+	}
+	for _, o := range []string{"discard", "ron", "skip", "next", "result"} {
+		operateWant(t, m, 0, &Operator{o, tileIdNone}, errorCodeWrongPhase, PhaseWaitingPlayers)
+	}
+	operateWant(t, m, 0, &Operator{"start", tileIdNone}, "", PhaseWaitingDiscard)
+
+	// deal -> discard
+	for _, o := range []string{"start", "ron", "skip", "next", "result"} {
+		operateWant(t, m, 0, &Operator{o, tileIdNone}, errorCodeWrongPhase, PhaseWaitingDiscard)
+	}
+	operateWant(t, m, (m.playerIdInTurn + 1) % playerNumber, &Operator{"discard", tileIdNone}, errorCodeNotYourTurn, PhaseWaitingDiscard)
+
+	// discard -> ron window
+	discarder, winner := setUpRon(t, m)
+	operateWant(t, m, discarder, &Operator{"discard", tileIdNone}, "", PhaseWaitingRon)
+	for _, o := range []string{"start", "discard", "next", "result"} {
+		operateWant(t, m, winner, &Operator{o, tileIdNone}, errorCodeWrongPhase, PhaseWaitingRon)
+	}
+	operateWant(t, m, discarder, &Operator{"ron", tileIdNone}, errorCodeCannotRon, PhaseWaitingRon)
+	operateWant(t, m, (winner + 1) % playerNumber, &Operator{"ron", tileIdNone}, errorCodeCannotRon, PhaseWaitingRon)
+
+	// ron window -> hand over
+	operateWant(t, m, winner, &Operator{"ron", tileIdNone}, "", PhaseHandOver)
+	for _, o := range []string{"start", "discard", "ron", "skip"} {
+		operateWant(t, m, winner, &Operator{o, tileIdNone}, errorCodeWrongPhase, PhaseHandOver)
+	}
+	operateWant(t, m, 0, &Operator{"result", tileIdNone}, errorCodeWrongPhase, PhaseHandOver)
+
+	// hand over -> deal -> discard
+	operateWant(t, m, 0, &Operator{"next", tileIdNone}, "", PhaseWaitingDiscard)
+	operateWant(t, m, 0, &Operator{"next", tileIdNone}, errorCodeWrongPhase, PhaseWaitingDiscard)
+
+	// hand over -> game over after the final round
+	m.round = &Round{m.ruleset.FinalWind(), roundNumber, 0}
+	discarder, winner = setUpRon(t, m)
+	operateWant(t, m, discarder, &Operator{"discard", tileIdNone}, "", PhaseWaitingRon)
+	operateWant(t, m, winner, &Operator{"ron", tileIdNone}, "", PhaseHandOver)
+	operateWant(t, m, 0, &Operator{"result", tileIdNone}, "", PhaseGameOver)
+	for _, o := range []string{"start", "discard", "ron", "skip", "next", "result"} {
+		operateWant(t, m, 0, &Operator{o, tileIdNone}, errorCodeWrongPhase, PhaseGameOver)
+	}
+}
+
+func TestPhaseDiscardWithoutRon(t *testing.T) {
+	m := newPhaseTestManager(t)
+	operateWant(t, m, 0, &Operator{"start", tileIdNone}, "", PhaseWaitingDiscard)
+	discarder, _ := setUpRon(t, m)
+	m.playerInfos[discarder].DrawnTile = mustParseTiles(t, "7z")[0]
+	operateWant(t, m, discarder, &Operator{"discard", tileIdNone}, "", PhaseWaitingDiscard)
+	if m.playerIdInTurn != (discarder + 1) % playerNumber {
+		t.Fatalf("got player %d in turn after %d", m.playerIdInTurn, discarder)
+	}
+}
+
+func TestPhaseExhaustedWall(t *testing.T) {
+	m := newPhaseTestManager(t)
+	operateWant(t, m, 0, &Operator{"start", tileIdNone}, "", PhaseWaitingDiscard)
+	discarder, _ := setUpRon(t, m)
+	m.playerInfos[discarder].DrawnTile = mustParseTiles(t, "7z")[0]
+	for m.wall.CanDraw() {
+		m.wall.Draw()
+	}
+	operateWant(t, m, discarder, &Operator{"discard", tileIdNone}, "", PhaseHandOver)
+	operateWant(t, m, 0, &Operator{"next", tileIdNone}, "", PhaseWaitingDiscard)
+}
+
+// Only a pinfu win counts for the order of the first pinfu.
+func TestTsumoWithoutPinfuKeepsFirstPinfuOrder(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetAny)
+	ruleset.Tsumo = true
+	m := &MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	operateWant(t, m, 0, &Operator{"start", tileIdNone}, "", PhaseWaitingDiscard)
+	p := m.playerInfos[m.playerIdInTurn]
+	p.Hands = mustParseTiles(t, "123456789m11p55z")
+	p.DrawnTile = mustParseTiles(t, "5z")[0]
+	m.CheckTsumo(p)
+	if !p.TsumoInfo.CanWin() || p.TsumoInfo.IsPinfu {
+		t.Fatalf("got %+v, want a win without pinfu", p.TsumoInfo)
+	}
+	operateWant(t, m, m.playerIdInTurn, &Operator{"tsumo", tileIdNone}, "", PhaseHandOver)
+	if p.WinnedPinfu() {
+		t.Fatalf("got first pinfu order %d for a win without pinfu", p.FirstPinfuOrder)
+	}
+}
+
+func TestRonWithoutPinfuKeepsFirstPinfuOrder(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetAny)
+	m := &MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	operateWant(t, m, 0, &Operator{"start", tileIdNone}, "", PhaseWaitingDiscard)
+	discarder, winner := setUpRon(t, m)
+	m.playerInfos[winner].Hands = mustParseTiles(t, "123456789m11p55z")
+	m.playerInfos[discarder].DrawnTile = mustParseTiles(t, "5z")[0]
+	if err := m.UpdateWinningTables(0, 1, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	operateWant(t, m, discarder, &Operator{"discard", tileIdNone}, "", PhaseWaitingRon)
+	p := m.playerInfos[winner]
+	if !p.PinfuInfo.CanWin() || p.PinfuInfo.IsPinfu {
+		t.Fatalf("got %+v, want a win without pinfu", p.PinfuInfo)
+	}
+	operateWant(t, m, winner, &Operator{"ron", tileIdNone}, "", PhaseHandOver)
+	if p.WinnedPinfu() {
+		t.Fatalf("got first pinfu order %d for a win without pinfu", p.FirstPinfuOrder)
+	}
+}
@@ -1,19 +1,22 @@
 package main
 
 import (
-	"log"
 	"bytes"
+	"context"
+	"fmt"
+	"time"
 	"net/http"
 	"io/ioutil"
 	"encoding/json"
 	"strconv"
-	"math"
 )
 
 const (
 	windEastOfPinfuQuery = 27
 )
 
+var winTileTypes = [...]string{"man", "pin", "sou", "honors"}
+
 type PinfuQuery struct {
 	Man string `json:"man"`
 	Pin string `json:"pin"`
@@ -25,66 +28,163 @@ type PinfuQuery struct {
 	WinTileValue string `json:"win_tile_value"`
 }
 
+// PinfuInfo is what a win on one tile is worth. A hand can win when it has any han,
+// and IsPinfu tells whether pinfu is among its yaku. WaitShape is the wait of the split
+// that was scored, empty when the evaluator does not tell.
 type PinfuInfo struct {
 	IsPinfu bool
 	Cost int
+	Han int
+	Fu int
+	Yaku []string
+	WaitShape WaitShape
+}
+
+func NewPinfuInfo(han int, fu int, playerWind Wind) *PinfuInfo {
+	score := NewScore(han, fu)
+	return &PinfuInfo{true, score.RonPayment(playerWind == EAST, 0), score.Han, score.Fu, []string{yakuPinfu}, WaitRyanmen}
+}
+
+func (p *PinfuInfo) CanWin() bool {
+	return p.Han > 0
+}
+
+// HTTPHandEvaluator evaluates hands with the hands-calculation API.
+// Each attempt has its own deadline, failed attempts are retried with backoff
+// and the circuit breaker fails fast while the API is unhealthy.
+type HTTPHandEvaluator struct {
+	url string
+	client *http.Client
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+	breaker *CircuitBreaker
+}
+
+func NewHTTPHandEvaluator(c *HandEvaluatorConfig) *HTTPHandEvaluator {
+	return &HTTPHandEvaluator{
+		url: c.CalculatorURL,
+		client: &http.Client{},
+		timeout: c.Timeout,
+		retries: c.Retries,
+		backoff: c.Backoff,
+		breaker: NewCircuitBreaker(c.BreakerThreshold, c.BreakerCooldown),
+	}
+}
+
+// Evaluate asks the API about a ron win. The API cannot be told about tsumo or riichi,
+// so NewHandEvaluator keeps this evaluator from rulesets that allow them.
+func (e *HTTPHandEvaluator) Evaluate(ctx context.Context, hands []Tile, ronTile Tile, situation WinSituation, playerWind Wind, roundWind Wind) (*PinfuInfo, error) {
+	if !e.breaker.Allow() {
+		return nil, ErrCircuitOpen
+	}
+	p := PinfuQuery{}
+	p.Parse(hands, ronTile, int(playerWind), int(roundWind))
+	logger.Debug("query", Hidden("query", p))
+
+	var err error
+	for attempt := 0; attempt <= e.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				e.fail(ctx)
+				return nil, ctx.Err()
+			case <-time.After(e.backoff * time.Duration(attempt)):
+			}
+		}
+		var pinfuInfo *PinfuInfo
+		pinfuInfo, err = e.query(ctx, &p)
+		if err == nil {
+			e.breaker.Success()
+			return pinfuInfo, nil
+		}
+		if ctx.Err() == context.Canceled {
+			break
+		}
+		logger.Warn("query failed", F("attempt", attempt + 1), F("error", err))
+	}
+	e.fail(ctx)
+	return nil, err
+}
+
+// fail records a failure on the breaker unless the caller cancelled the evaluation,
+// e.g. because another query of the same update failed, which says nothing about the API.
+func (e *HTTPHandEvaluator) fail(ctx context.Context) {
+	if ctx.Err() == context.Canceled {
+		e.breaker.Abandon()
+		return
+	}
+	e.breaker.Failure()
 }
 
-func (p *PinfuQuery) Query() *PinfuInfo {
-	url := "http://host.docker.internal:8000"
-	j, _ := json.Marshal(p)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(j))
+func (e *HTTPHandEvaluator) query(ctx context.Context, p *PinfuQuery) (*PinfuInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	return p.Query(ctx, e.client, e.url)
+}
+
+func (p *PinfuQuery) Query(ctx context.Context, client *http.Client, url string) (*PinfuInfo, error) {
+	j, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(j))
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, _ := ioutil.ReadAll(resp.Body)
-	log.Printf("response body:%s", body)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("response", F("status", resp.StatusCode), Hidden("body", string(body)))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
 	pinfuInfo := PinfuInfo {}
-	json.Unmarshal(body, &pinfuInfo)
-	return &pinfuInfo
+	if err := json.Unmarshal(body, &pinfuInfo); err != nil {
+		return nil, err
+	}
+	// older calculators answer only the cost
+	if pinfuInfo.IsPinfu && pinfuInfo.Han == 0 {
+		return NewPinfuInfo(hanPinfu, fuPinfuRon, Wind(p.PlayerWind - windEastOfPinfuQuery + 1)), nil
+	}
+	return &pinfuInfo, nil
 }
 
-func (p *PinfuQuery) Parse(hands []int, ronTileId int, playerWind int, roundWind int) {
-	hands = append(hands, ronTileId)
-	for _, tileId := range hands {
-		switch {
-		case tileId < 36:
-			p.Man += strconv.Itoa(int(math.Floor(float64(tileId/4))) + 1)
-		case tileId < 72:
-			p.Pin += strconv.Itoa(int(math.Floor(float64((tileId - 36)/4))) + 1)
-		case tileId < 108:
-			p.Sou += strconv.Itoa(int(math.Floor(float64((tileId - 72)/4))) + 1)
-		case tileId < 136:
-			p.Honors += strconv.Itoa(int(math.Floor(float64((tileId - 108)/4))) + 1)
+func (p *PinfuQuery) Parse(hands []Tile, ronTile Tile, playerWind int, roundWind int) {
+	tiles := make([]Tile, 0, len(hands) + 1)
+	tiles = append(tiles, hands...)
+	tiles = append(tiles, ronTile)
+	for _, t := range tiles {
+		switch t.Suit() {
+		case SuitMan:
+			p.Man += strconv.Itoa(t.Rank())
+		case SuitPin:
+			p.Pin += strconv.Itoa(t.Rank())
+		case SuitSou:
+			p.Sou += strconv.Itoa(t.Rank())
+		case SuitHonor:
+			p.Honors += strconv.Itoa(t.Rank())
 		}
 	}
 
 	p.PlayerWind = p.WindForPinfuQuery(playerWind)
 	p.RoundWind = p.WindForPinfuQuery(roundWind)
-	switch {
-	case ronTileId < 36:
-		p.WinTileType = "man"
-		p.WinTileValue = strconv.Itoa(int(math.Floor(float64(ronTileId/4))) + 1)
-	case ronTileId < 72:
-		p.WinTileType = "pin"
-		p.WinTileValue = strconv.Itoa(int(math.Floor(float64((ronTileId - 36)/4))) + 1)
-	case ronTileId < 108:
-		p.WinTileType = "sou"
-		p.WinTileValue = strconv.Itoa(int(math.Floor(float64((ronTileId - 72)/4))) + 1)
-	case ronTileId < 136:
-		p.WinTileType = "honors"
-		p.WinTileValue = strconv.Itoa(int(math.Floor(float64((ronTileId - 108)/4))) + 1)
-	}
-	if len(p.Sou) == 14 && p.WinTileType == "sou" {
+	p.WinTileType = winTileTypes[ronTile.Suit()]
+	p.WinTileValue = strconv.Itoa(ronTile.Rank())
+	// The calculator is sent all-sou hands as man. Pinfu does not depend on the suit.
+	if len(p.Sou) == len(tiles) && ronTile.Suit() == SuitSou {
 		p.Man = p.Sou
 		p.Sou = ""
-		p.WinTileType = "man"
+		p.WinTileType = winTileTypes[SuitMan]
 	}
 }
 
@@ -95,8 +195,10 @@ func (p *PinfuQuery) WindForPinfuQuery(wind int) int {
 /*
 func main() {
 	p := PinfuQuery{}
-	p.Parse([]int{0,1,12,16,20,24,28,32,60,64,68,76,80}, 84, 27, 27)
+	hands, _ := ParseTiles("11456789m789p23s")
+	ronTile, _ := ParseTiles("4s")
+	p.Parse(hands, ronTile[0], int(EAST), int(EAST))
 	log.Println(p)
-	p.Query()
+	p.Query(context.Background(), &http.Client{}, "http://localhost:8000")
 }
 */
\ No newline at end of file
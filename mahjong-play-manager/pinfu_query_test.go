@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testHTTPHandEvaluator(url string, retries int, threshold int) *HTTPHandEvaluator {
+	return NewHTTPHandEvaluator(&HandEvaluatorConfig{CalculatorURL: url, Timeout: time.Second, Retries: retries, Backoff: time.Millisecond, BreakerThreshold: threshold, BreakerCooldown: 50 * time.Millisecond})
+}
+
+func TestHTTPHandEvaluatorRetriesAndBreaker(t *testing.T) {
+	var calls int32
+	var healthy int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"isPinfu":true,"cost":1000}`))
+	}))
+	defer server.Close()
+
+	e := testHTTPHandEvaluator(server.URL, 2, 2)
+	hands := mustParseTiles(t, "11456789m789p23s")
+	ronTile := mustParseTiles(t, "4s")[0]
+	for i := 0; i < 2; i++ {
+		if _, err := e.Evaluate(context.Background(), hands, ronTile, WinSituation{}, EAST, EAST); err == nil {
+			t.Fatal("want an error from an unhealthy calculator")
+		}
+	}
+	if calls != 6 {
+		t.Fatalf("got %d calls, want 3 attempts for each evaluation", calls)
+	}
+	if _, err := e.Evaluate(context.Background(), hands, ronTile, WinSituation{}, EAST, EAST); err != ErrCircuitOpen {
+		t.Fatalf("got %v, want %v", err, ErrCircuitOpen)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&healthy, 1)
+	pinfuInfo, err := e.Evaluate(context.Background(), hands, ronTile, WinSituation{}, EAST, EAST)
+	if err != nil || !pinfuInfo.IsPinfu || pinfuInfo.Han != hanPinfu {
+		t.Fatal(pinfuInfo, err)
+	}
+}
+
+// One failing query cancels the others of the same update, and the cancelled ones
+// must not count against the calculator.
+func TestHTTPHandEvaluatorCancelledQueriesDoNotTripBreaker(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := PinfuQuery{}
+		json.NewDecoder(r.Body).Decode(&p)
+		if p.WinTileValue == "5" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	e := testHTTPHandEvaluator(server.URL, 0, 2)
+	m := MahjongPlayManager{}
+	m.Init(e, testRuleset(t, yakuSetPinfu))
+	m.playerInfos[0].Hands = mustParseTiles(t, "2345678m456p111z")
+	if n := len(winningKinds(m.playerInfos[0].Hands)); n != 3 {
+		t.Fatalf("got %d waits, want 3 concurrent queries", n)
+	}
+	if err := m.UpdateWinningTables(0); err == nil {
+		t.Fatal("want the error of the failing query")
+	}
+	if e.breaker.state != circuitClosed || e.breaker.failures != 1 {
+		t.Fatalf("got breaker state %d with %d failures, want closed with 1", e.breaker.state, e.breaker.failures)
+	}
+}
+
+func TestCircuitBreakerAbandonedTrial(t *testing.T) {
+	b := NewCircuitBreaker(1, 0)
+	b.Failure()
+	if !b.Allow() {
+		t.Fatal("want a trial call after the cooldown")
+	}
+	if b.Allow() {
+		t.Fatal("want a single trial call while half-open")
+	}
+	b.Abandon()
+	if !b.Allow() {
+		t.Fatal("want another trial call after the trial was abandoned")
+	}
+}
+
+// After the cooldown the trial call of a multi-wait update goes alone,
+// so the other queries of the update do not cancel it and the breaker closes.
+func TestHTTPHandEvaluatorMultiWaitUpdateClosesBreaker(t *testing.T) {
+	var healthy int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"isPinfu":true,"cost":1000}`))
+	}))
+	defer server.Close()
+
+	e := testHTTPHandEvaluator(server.URL, 0, 1)
+	m := MahjongPlayManager{}
+	m.Init(e, testRuleset(t, yakuSetPinfu))
+	m.playerInfos[0].Hands = mustParseTiles(t, "2345678m456p111z")
+	if err := m.UpdateWinningTables(0); err == nil {
+		t.Fatal("want an error from an unhealthy calculator")
+	}
+	if e.breaker.state != circuitOpen {
+		t.Fatalf("got breaker state %d, want open", e.breaker.state)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&healthy, 1)
+	if err := m.UpdateWinningTables(0); err != nil {
+		t.Fatal(err)
+	}
+	if e.breaker.state != circuitClosed {
+		t.Fatalf("got breaker state %d, want closed", e.breaker.state)
+	}
+	if n := len(m.playerInfos[0].WinningTable); n != 3 {
+		t.Fatalf("got %d winning kinds, want 3", n)
+	}
+}
+
+func TestHTTPHandEvaluatorSendsAllSouAsMan(t *testing.T) {
+	queries := make(chan PinfuQuery, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := PinfuQuery{}
+		json.NewDecoder(r.Body).Decode(&p)
+		queries <- p
+		w.Write([]byte(`{"isPinfu":true,"cost":1000}`))
+	}))
+	defer server.Close()
+
+	e := testHTTPHandEvaluator(server.URL, 0, 1)
+	if _, err := e.Evaluate(context.Background(), mustParseTiles(t, "23456789s123s55s"), mustParseTiles(t, "1s")[0], WinSituation{}, SOUTH, EAST); err != nil {
+		t.Fatal(err)
+	}
+	want := PinfuQuery{"23456789123551", "", "", "", 28, 27, "man", "1"}
+	if p := <-queries; p != want {
+		t.Fatalf("got %+v, want %+v", p, want)
+	}
+}
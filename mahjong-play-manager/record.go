@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+const (
+	operationTimeout = "timeout"
+)
+
+// Action is one applied operation of a game. A ron window that timed out is recorded
+// as a timeout with the window as the target. Resuming a paused table is not recorded,
+// since a pause comes from the hand evaluator and not from the game.
+type Action struct {
+	PlayerId int `json:"playerId"`
+	Operation string `json:"operation"`
+	Target int `json:"target"`
+}
+
+// GameRecord is what is needed to play a game again: the hex of its seed and its actions in order.
+type GameRecord struct {
+	Seed string `json:"seed"`
+	Actions []*Action `json:"actions"`
+}
+
+func (m *MahjongPlayManager) Record() *GameRecord {
+	return m.record
+}
+
+func (m *MahjongPlayManager) recordAction(playerId int, operation string, target int) {
+	m.record.Actions = append(m.record.Actions, &Action{playerId, operation, target})
+}
+
+func (r *GameRecord) ToBytes() []byte {
+	bytes, _ := json.Marshal(r)
+	return bytes
+}
+
+func LoadGameRecord(path string) (*GameRecord, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	record := &GameRecord{}
+	if err := json.Unmarshal(body, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Replay plays a recorded game again and returns it in its final state. Timeouts come from
+// the record, so the ruleset's timeout is not used, and resumes in older records are skipped.
+// The hand evaluator should be deterministic, which the mock evaluator is not when a hand
+// has several winning tiles.
+func Replay(record *GameRecord, handEvaluator HandEvaluator, ruleset *Ruleset) (*MahjongPlayManager, error) {
+	replayRuleset := *ruleset
+	replayRuleset.RonTimeout = 0
+	seed, err := ParseSeed(record.Seed)
+	if err != nil {
+		return nil, err
+	}
+	m := &MahjongPlayManager{}
+	m.Init(handEvaluator, &replayRuleset)
+	m.SetSeed(seed)
+	for i, a := range record.Actions {
+		if a.Operation == operationTimeout {
+			if !m.TimeoutRonWindow(a.Target) {
+				return m, fmt.Errorf("action %d: ron window %d is not open", i, a.Target)
+			}
+			continue
+		}
+		o := &Operator{a.Operation, a.Target}
+		if o.isResume() {
+			continue
+		}
+		if err := m.Operate(a.PlayerId, o); err != nil {
+			return m, fmt.Errorf("action %d: %v", i, err)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// flakyHandEvaluator fails the first evaluation of each hand won on a kind that is a multiple
+// of failEvery and leaves the others to evaluator, so that which evaluations fail depends
+// only on the hands and not on the order the queries of an update run in.
+type flakyHandEvaluator struct {
+	evaluator HandEvaluator
+	failEvery int
+	failed map[string]bool
+	mux sync.Mutex
+}
+
+func newFlakyHandEvaluator(evaluator HandEvaluator, failEvery int) *flakyHandEvaluator {
+	return &flakyHandEvaluator{evaluator: evaluator, failEvery: failEvery, failed: map[string]bool{}}
+}
+
+func (e *flakyHandEvaluator) Evaluate(ctx context.Context, hands []Tile, ronTile Tile, situation WinSituation, playerWind Wind, roundWind Wind) (*PinfuInfo, error) {
+	if ronTile.Kind()%e.failEvery == 0 {
+		e.mux.Lock()
+		key := FormatTiles(hands) + ronTile.String()
+		failed := e.failed[key]
+		e.failed[key] = true
+		e.mux.Unlock()
+		if !failed {
+			return nil, errors.New("evaluation failed")
+		}
+	}
+	return e.evaluator.Evaluate(ctx, hands, ronTile, situation, playerWind, roundWind)
+}
+
+// chooseDiscard picks at random one of the discards that leave the hands closest to tenpai,
+// tileIdNone being the drawn tile.
+func chooseDiscard(m *MahjongPlayManager, r *rand.Rand) int {
+	p := m.playerInfos[m.playerIdInTurn]
+	best := []int{}
+	shanten := tileInHandNumber
+	for position := tileIdNone; position < len(p.Hands); position++ {
+		hands := append([]Tile{}, p.Hands...)
+		if position != tileIdNone {
+			hands[position] = p.DrawnTile
+		}
+		s := Shanten(hands)
+		if s < shanten {
+			best = best[:0]
+			shanten = s
+		}
+		if s == shanten {
+			best = append(best, position)
+		}
+	}
+	return best[r.Intn(len(best))]
+}
+
+// playRecordedGame plays a whole game winning every other ron window
+// and letting the rest time out, and resumes whenever the evaluator paused the table.
+func playRecordedGame(t *testing.T, m *MahjongPlayManager, r *rand.Rand) int {
+	resumes := 0
+	operate := func(playerId int, o *Operator) {
+		if err := m.Operate(playerId, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	operate(0, &Operator{"start", tileIdNone})
+	for steps := 0; m.Phase() != PhaseGameOver; steps++ {
+		if steps > 10000 {
+			t.Fatal("the game did not end")
+		}
+		switch {
+		case m.IsPaused():
+			operate(r.Intn(playerNumber), &Operator{"resume", tileIdNone})
+			resumes++
+		case m.Phase() == PhaseWaitingDiscard:
+			operate(m.playerIdInTurn, &Operator{"discard", chooseDiscard(m, r)})
+		case m.Phase() == PhaseWaitingRon:
+			if candidates := m.ronCandidates(); m.ronWindow%2 == 0 {
+				operate(candidates[0], &Operator{"ron", tileIdNone})
+			} else {
+				m.TimeoutRonWindow(m.ronWindow)
+			}
+		case m.Phase() == PhaseHandOver && m.continueGame():
+			operate(r.Intn(playerNumber), &Operator{"next", tileIdNone})
+		case m.Phase() == PhaseHandOver:
+			operate(r.Intn(playerNumber), &Operator{"result", tileIdNone})
+		}
+	}
+	return resumes
+}
+
+func TestReplayRecordedGame(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetAny)
+	ruleset.RonTimeout = 0
+	m := &MahjongPlayManager{}
+	m.Init(newFlakyHandEvaluator(&NativeHandEvaluator{ruleset}, 7), ruleset)
+	m.SetSeed(bytes.Repeat([]byte{1}, seedByteNumber))
+	if resumes := playRecordedGame(t, m, rand.New(rand.NewSource(1))); resumes == 0 {
+		t.Fatal("the table was never paused")
+	}
+
+	b, err := json.Marshal(m.Record())
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := &GameRecord{}
+	if err := json.Unmarshal(b, record); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range record.Actions {
+		if (&Operator{a.Operation, a.Target}).isResume() {
+			t.Fatalf("a resume was recorded: %+v", a)
+		}
+	}
+
+	replayed, err := Replay(record, &NativeHandEvaluator{ruleset}, ruleset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Phase() != PhaseGameOver || !reflect.DeepEqual(replayed.round, m.round) || !reflect.DeepEqual(replayed.wall, m.wall) {
+		t.Fatalf("got %s in %+v, want %s in %+v", replayed.Phase(), replayed.round, m.Phase(), m.round)
+	}
+	for i, p := range m.playerInfos {
+		q := replayed.playerInfos[i]
+		if q.Point != p.Point || q.Wind != p.Wind || !reflect.DeepEqual(q.Hands, p.Hands) || !reflect.DeepEqual(q.River, p.River) {
+			t.Errorf("player %d: got %d points, hands %v and river %v, want %d points, hands %v and river %v", i, q.Point, q.Hands, q.River, p.Point, p.Hands, p.River)
+		}
+	}
+}
+
+func TestReplaySkipsRecordedResume(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	record := &GameRecord{hex.EncodeToString(NewSeed()), []*Action{{0, "start", tileIdNone}, {1, "resume", tileIdNone}}}
+	m, err := Replay(record, &NativeHandEvaluator{ruleset}, ruleset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Phase() != PhaseWaitingDiscard {
+		t.Fatalf("got %s", m.Phase())
+	}
+}
+
+func TestReplayRejectsInvalidSeed(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	record := &GameRecord{"12345", []*Action{{0, "start", tileIdNone}}}
+	if _, err := Replay(record, &NativeHandEvaluator{ruleset}, ruleset); err == nil {
+		t.Fatal("want an error for a seed of 12345")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+const (
+	riichiDeposit = 1000
+	riichiMinLiveTiles = playerNumber
+)
+
+// Riichi declares riichi with the discard at position. The deposit is only taken once the
+// discard is not won, see acceptRiichi, and ippatsu lasts until the next discard of the player.
+func (m *MahjongPlayManager) Riichi(position int) {
+	p := m.playerInfos[m.playerIdInTurn]
+	discardedTile := m.DiscardTile(position)
+	p.Riichi = true
+	p.Ippatsu = true
+	m.pendingRiichi = m.playerIdInTurn
+	m.ProceedDiscard(discardedTile)
+}
+
+// acceptRiichi places the deposit of a riichi whose declaration discard was not won.
+func (m *MahjongPlayManager) acceptRiichi() {
+	if m.pendingRiichi == playerIdNone {
+		return
+	}
+	m.playerInfos[m.pendingRiichi].Point -= riichiDeposit
+	m.riichiSticks++
+	m.pendingRiichi = playerIdNone
+}
+
+// CheckRiichi tells the player in turn whether riichi can be declared with some discard.
+func (m *MahjongPlayManager) CheckRiichi(p *PlayerInfo) {
+	p.CanRiichi = false
+	if !m.canDeclareRiichi(p) {
+		return
+	}
+	for position := tileIdNone; position < len(p.Hands); position++ {
+		if m.isTenpaiAfterDiscard(p, position) {
+			p.CanRiichi = true
+			return
+		}
+	}
+}
+
+// canDeclareRiichi checks everything but the hands: the rule, the points for the deposit
+// and a draw left for every player.
+func (m *MahjongPlayManager) canDeclareRiichi(p *PlayerInfo) bool {
+	return m.ruleset.Riichi && !p.Riichi && p.Point >= riichiDeposit && m.wall.LiveTileNumber() >= riichiMinLiveTiles
+}
+
+// isTenpaiAfterDiscard tells whether the hands are tenpai after discarding the tile at position,
+// the drawn tile when position is not in the hands.
+func (m *MahjongPlayManager) isTenpaiAfterDiscard(p *PlayerInfo, position int) bool {
+	hands := append([]Tile{}, p.Hands...)
+	if position >= 0 && position < len(hands) {
+		hands[position] = p.DrawnTile
+	}
+	return len(winningKinds(hands)) > 0
+}
+
+// riichiSticksPoints is what the deposits on the table are worth to the next winner.
+func (m *MahjongPlayManager) riichiSticksPoints() int {
+	return m.riichiSticks * riichiDeposit
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"testing"
+)
+
+// newRiichiTestManager starts a game with riichi where the player in turn holds hands that are
+// tenpai on 3z after discarding the drawn 9p, and the others have no wait.
+func newRiichiTestManager(t *testing.T) (*MahjongPlayManager, *PlayerInfo) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	ruleset.Riichi = true
+	ruleset.RonTimeout = 0
+	m := &MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	operateWant(t, m, 0, &Operator{"start", tileIdNone}, "", PhaseWaitingDiscard)
+	for _, p := range m.playerInfos {
+		p.Hands = mustParseTiles(t, "1479m258p369s1234z")
+	}
+	p := m.playerInfos[m.playerIdInTurn]
+	p.Hands = mustParseTiles(t, "123m456p789s123s3z")
+	p.DrawnTile = mustParseTiles(t, "9p")[0]
+	if err := m.UpdateWinningTables(0, 1, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	return m, p
+}
+
+func TestRiichiPreconditions(t *testing.T) {
+	tests := []struct {
+		name string
+		setUp func(m *MahjongPlayManager, p *PlayerInfo)
+		wantCode string
+	}{
+		{"tenpai", func(m *MahjongPlayManager, p *PlayerInfo) {}, ""},
+		{"not tenpai", func(m *MahjongPlayManager, p *PlayerInfo) {
+			p.Hands = mustParseTiles(t, "1479m258p369s1234z")
+			p.DrawnTile = mustParseTiles(t, "5z")[0]
+		}, errorCodeCannotRiichi},
+		{"exactly 1000 points", func(m *MahjongPlayManager, p *PlayerInfo) {
+			p.Point = riichiDeposit
+		}, ""},
+		{"under 1000 points", func(m *MahjongPlayManager, p *PlayerInfo) {
+			p.Point = riichiDeposit - 100
+		}, errorCodeCannotRiichi},
+		{"4 tiles left", func(m *MahjongPlayManager, p *PlayerInfo) {
+			for m.wall.LiveTileNumber() > riichiMinLiveTiles {
+				m.wall.Draw()
+			}
+		}, ""},
+		{"3 tiles left", func(m *MahjongPlayManager, p *PlayerInfo) {
+			for m.wall.LiveTileNumber() > riichiMinLiveTiles - 1 {
+				m.wall.Draw()
+			}
+		}, errorCodeCannotRiichi},
+		{"already in riichi", func(m *MahjongPlayManager, p *PlayerInfo) {
+			p.Riichi = true
+		}, errorCodeCannotRiichi},
+		{"the rule off", func(m *MahjongPlayManager, p *PlayerInfo) {
+			m.ruleset.Riichi = false
+		}, errorCodeCannotRiichi},
+	}
+	for _, test := range tests {
+		m, p := newRiichiTestManager(t)
+		test.setUp(m, p)
+		m.CheckRiichi(p)
+		if p.CanRiichi != (test.wantCode == "") {
+			t.Errorf("%s: got can riichi %t", test.name, p.CanRiichi)
+		}
+		err := m.ValidateOperation(p.PlayerId, &Operator{"riichi", tileIdNone})
+		switch {
+		case test.wantCode == "" && err != nil:
+			t.Errorf("%s: got %v", test.name, err)
+		case test.wantCode != "" && (err == nil || err.Code != test.wantCode):
+			t.Errorf("%s: got %v, want %s", test.name, err, test.wantCode)
+		}
+	}
+
+	// a discard that leaves the hands not tenpai cannot declare riichi
+	m, p := newRiichiTestManager(t)
+	if err := m.ValidateOperation(p.PlayerId, &Operator{"riichi", 0}); err == nil || err.Code != errorCodeCannotRiichi {
+		t.Errorf("discarding 1m: got %v, want %s", err, errorCodeCannotRiichi)
+	}
+}
+
+func TestRiichiTsumogiriLock(t *testing.T) {
+	m, p := newRiichiTestManager(t)
+	operateWant(t, m, p.PlayerId, &Operator{"riichi", tileIdNone}, "", PhaseWaitingDiscard)
+	if !p.Riichi || p.Point != defaultStartPoints - riichiDeposit || m.riichiSticks != 1 {
+		t.Fatalf("got riichi %t, %d points and %d sticks", p.Riichi, p.Point, m.riichiSticks)
+	}
+	for m.playerIdInTurn != p.PlayerId {
+		discardDrawn(t, m, "7z", PhaseWaitingDiscard)
+	}
+	hands := append([]Tile{}, p.Hands...)
+	p.DrawnTile = mustParseTiles(t, "5z")[0]
+	operateWant(t, m, p.PlayerId, &Operator{"discard", 0}, errorCodeRiichiLocked, PhaseWaitingDiscard)
+	operateWant(t, m, p.PlayerId, &Operator{"riichi", tileIdNone}, errorCodeCannotRiichi, PhaseWaitingDiscard)
+	operateWant(t, m, p.PlayerId, &Operator{"discard", tileIdNone}, "", PhaseWaitingDiscard)
+	if FormatTiles(p.Hands) != FormatTiles(hands) {
+		t.Fatalf("got hands %s, want %s", FormatTiles(p.Hands), FormatTiles(hands))
+	}
+}
+
+// The deposit stays on the table through a drawn hand and goes to the first winner only.
+func TestRiichiDeposit(t *testing.T) {
+	m, p := newRiichiTestManager(t)
+	for m.wall.LiveTileNumber() > riichiMinLiveTiles {
+		m.wall.Draw()
+	}
+	operateWant(t, m, p.PlayerId, &Operator{"riichi", tileIdNone}, "", PhaseWaitingDiscard)
+	for m.Phase() == PhaseWaitingDiscard {
+		discardDrawn(t, m, "7z", PhaseWaitingDiscard)
+		if !m.wall.CanDraw() {
+			break
+		}
+	}
+	m.playerInfos[m.playerIdInTurn].DrawnTile = mustParseTiles(t, "6z")[0]
+	operateWant(t, m, m.playerIdInTurn, &Operator{"discard", tileIdNone}, "", PhaseHandOver)
+	if m.riichiSticks != 1 {
+		t.Fatalf("got %d sticks after the drawn hand, want 1", m.riichiSticks)
+	}
+	operateWant(t, m, 0, &Operator{"next", tileIdNone}, "", PhaseWaitingDiscard)
+	if m.riichiSticks != 1 {
+		t.Fatalf("got %d sticks in the next hand, want 1", m.riichiSticks)
+	}
+
+	discarder := m.playerIdInTurn
+	first := (discarder + 1) % playerNumber
+	second := (discarder + 2) % playerNumber
+	for _, playerId := range []int{first, second} {
+		m.playerInfos[playerId].PinfuInfo = NewPinfuInfo(hanPinfu, fuPinfuRon, m.playerInfos[playerId].Wind)
+	}
+	ronInfo := m.CalculateRonInfo(first, second)
+	cost := NewScore(hanPinfu, fuPinfuRon).RonPayment(false, 0)
+	if got := ronInfo[first].PointDiff; got != cost + riichiDeposit {
+		t.Errorf("got %d for the first winner, want %d", got, cost + riichiDeposit)
+	}
+	if got := ronInfo[second].PointDiff; got != cost {
+		t.Errorf("got %d for the second winner, want %d", got, cost)
+	}
+}
+
+// Ippatsu lasts only until the next discard of the player in riichi, and a new hand clears it.
+// The game has no calls, so nothing else can break it.
+func TestIppatsuClears(t *testing.T) {
+	m, p := newRiichiTestManager(t)
+	operateWant(t, m, p.PlayerId, &Operator{"riichi", tileIdNone}, "", PhaseWaitingDiscard)
+	if !p.Ippatsu {
+		t.Fatal("no ippatsu after the declaration")
+	}
+	for m.playerIdInTurn != p.PlayerId {
+		discardDrawn(t, m, "7z", PhaseWaitingDiscard)
+		if !p.Ippatsu {
+			t.Fatal("ippatsu cleared by the discard of another player")
+		}
+	}
+	discardDrawn(t, m, "5z", PhaseWaitingDiscard)
+	if p.Ippatsu {
+		t.Fatal("ippatsu kept after the next own discard")
+	}
+
+	p.Ippatsu = true
+	m.InitRound()
+	if p.Ippatsu || p.Riichi {
+		t.Fatal("ippatsu or riichi kept into a new hand")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import "time"
+
+// OpenRonWindow starts collecting a ron or skip from every player who can win the last discard.
+// Players who have not answered when the ruleset's timeout passes are taken as skipping.
+func (m *MahjongPlayManager) OpenRonWindow() {
+	m.ronDecisions = map[int]bool{}
+	m.ronWindow++
+	window := m.ronWindow
+	if m.ruleset.RonTimeout > 0 {
+		time.AfterFunc(m.ruleset.RonTimeout, func() {
+			select {
+			case m.timeouts <- window:
+			case <-m.quit:
+			}
+		})
+	}
+	m.setPhase(PhaseWaitingRon)
+}
+
+// Decide records the answer of a player on the last discard and resolves the window
+// once every player who can win has answered.
+func (m *MahjongPlayManager) Decide(playerId int, isRon bool) {
+	m.ronDecisions[playerId] = isRon
+	logger.Debug("ron decision", F("playerId", playerId), Hidden("ron", isRon))
+	for _, id := range m.ronCandidates() {
+		if _, ok := m.ronDecisions[id]; !ok {
+			return
+		}
+	}
+	m.resolveRon()
+}
+
+// TimeoutRonWindow resolves the window when it is still the one the timeout was set for.
+func (m *MahjongPlayManager) TimeoutRonWindow(window int) bool {
+	if m.phase != PhaseWaitingRon || window != m.ronWindow {
+		return false
+	}
+	logger.Info("ron window timed out", F("window", window))
+	m.recordAction(playerIdNone, operationTimeout, window)
+	m.resolveRon()
+	return true
+}
+
+func (m *MahjongPlayManager) HasDecided(playerId int) bool {
+	_, ok := m.ronDecisions[playerId]
+	return ok
+}
+
+// ronCandidates lists the players who can win the last discard in seat order after the discarder.
+func (m *MahjongPlayManager) ronCandidates() []int {
+	candidates := []int{}
+	for i := 1; i < playerNumber; i++ {
+		playerId := (m.playerIdInTurn + i) % playerNumber
+		if m.playerInfos[playerId].PinfuInfo.CanWin() {
+			candidates = append(candidates, playerId)
+		}
+	}
+	return candidates
+}
+
+// resolveRon lets the claimed rons win, only the nearest one to the discarder unless the ruleset
+// allows multiple ron, and moves the turn on when nobody claimed.
+func (m *MahjongPlayManager) resolveRon() {
+	winners := []int{}
+	for _, playerId := range m.ronCandidates() {
+		if m.ronDecisions[playerId] {
+			winners = append(winners, playerId)
+		}
+	}
+	switch {
+	case len(winners) == 0:
+		m.Skip()
+	case m.ruleset.MultipleRon:
+		m.Ron(winners...)
+	default:
+		m.Ron(winners[0])
+	}
+}
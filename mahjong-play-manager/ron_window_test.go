@@ -0,0 +1,181 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// openTestRonWindow has discarder discard a 1m that the seats waiters after it wait on,
+// leaving the other seats without a wait, and returns in the ron window.
+func openTestRonWindow(t *testing.T, ruleset *Ruleset, discarder int, waiters ...int) *MahjongPlayManager {
+	t.Helper()
+	m := &MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	operateWant(t, m, 0, &Operator{"start", tileIdNone}, "", PhaseWaitingDiscard)
+	m.playerIdInTurn = discarder
+	for i, p := range m.playerInfos {
+		if i != discarder {
+			p.Hands = mustParseTiles(t, "1479m258p369s1234z")
+		}
+	}
+	for _, waiter := range waiters {
+		m.playerInfos[(discarder + waiter) % playerNumber].Hands = mustParseTiles(t, "23m456789p123s55s")
+	}
+	m.playerInfos[discarder].DrawnTile = mustParseTiles(t, "1m")[0]
+	if err := m.UpdateWinningTables(0, 1, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	operateWant(t, m, discarder, &Operator{"discard", tileIdNone}, "", PhaseWaitingRon)
+	return m
+}
+
+// checkRonWinners checks that only winners, in seat order, got paid by the discarder,
+// or that the turn moved on when there is no winner.
+func checkRonWinners(t *testing.T, m *MahjongPlayManager, discarder int, winners []int) {
+	t.Helper()
+	if len(winners) == 0 {
+		if m.Phase() != PhaseWaitingDiscard || m.playerIdInTurn != (discarder + 1) % playerNumber {
+			t.Fatalf("got %s with %d in turn, want %s with %d in turn", m.Phase(), m.playerIdInTurn, PhaseWaitingDiscard, (discarder + 1) % playerNumber)
+		}
+		return
+	}
+	if m.Phase() != PhaseHandOver {
+		t.Fatalf("got %s, want %s", m.Phase(), PhaseHandOver)
+	}
+	paid := []int{}
+	for i, p := range m.playerInfos {
+		if i != discarder && p.Point != defaultStartPoints {
+			paid = append(paid, i)
+		}
+	}
+	if !reflect.DeepEqual(paid, winners) {
+		t.Fatalf("got %v paid, want %v", paid, winners)
+	}
+	if lost := defaultStartPoints - m.playerInfos[discarder].Point; lost != 1000 * len(winners) {
+		t.Fatalf("got %d lost by the discarder, want %d", lost, 1000 * len(winners))
+	}
+}
+
+func TestRonCandidatesOrder(t *testing.T) {
+	tests := []struct {
+		waiters []int
+		want []int
+	}{
+		{[]int{1, 2, 3}, []int{1, 2, 3}},
+		{[]int{3, 1}, []int{1, 3}},
+		{[]int{3, 2}, []int{2, 3}},
+		{[]int{2}, []int{2}},
+	}
+	for discarder := 0; discarder < playerNumber; discarder++ {
+		for _, test := range tests {
+			m := openTestRonWindow(t, testRuleset(t, yakuSetPinfu), discarder, test.waiters...)
+			want := []int{}
+			for _, seat := range test.want {
+				want = append(want, (discarder + seat) % playerNumber)
+			}
+			if got := m.ronCandidates(); !reflect.DeepEqual(got, want) {
+				t.Errorf("discarder %d, waiters %v: got %v, want %v", discarder, test.waiters, got, want)
+			}
+		}
+	}
+}
+
+// The decisions are seats after the discarder in the order they answer, true for a ron.
+func TestResolveRon(t *testing.T) {
+	tests := []struct {
+		name string
+		multipleRon bool
+		seats []int
+		decisions []bool
+		want []int
+	}{
+		{"atamahane", false, []int{1, 2, 3}, []bool{true, true, true}, []int{1}},
+		{"atamahane answered from the far seat", false, []int{3, 2, 1}, []bool{true, true, true}, []int{1}},
+		{"atamahane after a skip", false, []int{1, 2, 3}, []bool{false, true, true}, []int{2}},
+		{"multiple ron", true, []int{1, 2, 3}, []bool{true, true, true}, []int{1, 2, 3}},
+		{"multiple ron after a skip", true, []int{3, 2, 1}, []bool{true, false, true}, []int{1, 3}},
+		{"every seat skips", false, []int{1, 2, 3}, []bool{false, false, false}, []int{}},
+		{"every seat skips with multiple ron", true, []int{2, 1, 3}, []bool{false, false, false}, []int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ruleset := testRuleset(t, yakuSetPinfu)
+			ruleset.MultipleRon = test.multipleRon
+			ruleset.RonTimeout = 0
+			m := openTestRonWindow(t, ruleset, 0, 1, 2, 3)
+			for i, seat := range test.seats {
+				operation := "skip"
+				if test.decisions[i] {
+					operation = "ron"
+				}
+				if i < len(test.seats) - 1 {
+					operateWant(t, m, seat, &Operator{operation, tileIdNone}, "", PhaseWaitingRon)
+				} else if err := m.Operate(seat, &Operator{operation, tileIdNone}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			checkRonWinners(t, m, 0, test.want)
+		})
+	}
+}
+
+// A timeout takes the seats that have not answered as skipping.
+func TestRonWindowTimeoutBeforeEveryDecision(t *testing.T) {
+	tests := []struct {
+		name string
+		multipleRon bool
+		rons []int
+		skips []int
+		want []int
+	}{
+		{"nobody answered", false, []int{}, []int{}, []int{}},
+		{"the nearest seat did not answer", false, []int{2}, []int{}, []int{2}},
+		{"the nearest seat skipped", false, []int{}, []int{1}, []int{}},
+		{"multiple ron with a seat that did not answer", true, []int{1, 3}, []int{}, []int{1, 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ruleset := testRuleset(t, yakuSetPinfu)
+			ruleset.MultipleRon = test.multipleRon
+			ruleset.RonTimeout = 0
+			m := openTestRonWindow(t, ruleset, 0, 1, 2, 3)
+			for _, seat := range test.rons {
+				operateWant(t, m, seat, &Operator{"ron", tileIdNone}, "", PhaseWaitingRon)
+			}
+			for _, seat := range test.skips {
+				operateWant(t, m, seat, &Operator{"skip", tileIdNone}, "", PhaseWaitingRon)
+			}
+			if m.TimeoutRonWindow(m.ronWindow - 1) {
+				t.Fatal("a timeout of an earlier window resolved this one")
+			}
+			if !m.TimeoutRonWindow(m.ronWindow) {
+				t.Fatal("the timeout did not resolve the window")
+			}
+			checkRonWinners(t, m, 0, test.want)
+			if m.TimeoutRonWindow(m.ronWindow) {
+				t.Fatal("the timeout resolved the window twice")
+			}
+		})
+	}
+}
+
+func TestRonTimeoutFiresInEngine(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	ruleset.RonTimeout = 100 * time.Millisecond
+	m := openTestRonWindow(t, ruleset, 0, 1, 2)
+	m.lastSnapshot = m.Snapshot()
+	outbound := make(chan [][]byte, 1)
+	go m.Run(func(messages [][]byte) { outbound <- messages })
+	defer m.Stop()
+
+	if err := m.Submit(2, &Operator{"ron", tileIdNone}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-outbound:
+	case <-time.After(time.Second):
+		t.Fatal("the ron window did not time out")
+	}
+	checkRonWinners(t, m, 0, []int{2})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+const (
+	defaultRoomId = "default"
+)
+
+var roomIdPattern = regexp.MustCompile(`^[0-9A-Za-z_-]{1,32}$`)
+
+// Room is one table with its own game and hub. clients counts the connections that joined it.
+// rules is the name of the rule file the room was created with, empty for the server's ruleset.
+type Room struct {
+	id string
+	rules string
+	manager *MahjongPlayManager
+	hub *Hub
+	clients int
+}
+
+// Seat seats a client that joined the room. The game starts when the table fills for the first time,
+// and a client that takes over a seat of a started game gets the state of the seat.
+func (r *Room) Seat(client *Client) {
+	start, takeover := r.hub.TakeSeat(client)
+	if start {
+		if err := r.manager.Submit(client.PlayerId(), &Operator{"start", tileIdNone}); err != nil {
+			logger.Warn("start rejected", F("room", r.id), F("code", err.Code))
+		}
+	}
+	if takeover {
+		r.manager.Resend(client.PlayerId())
+	}
+}
+
+// RoomManager keeps the rooms of the server by ID. A room is created when the first
+// connection joins it and destroyed when the last one leaves.
+type RoomManager struct {
+	rooms map[string]*Room
+	config *HandEvaluatorConfig
+	seed []byte
+	rulesDir string
+	mux sync.Mutex
+}
+
+// NewRoomManager gives rooms that deal from random seeds, or all from seed when it is set.
+// Rooms are played with the ruleset of config unless created with a rule file from rulesDir.
+func NewRoomManager(config *HandEvaluatorConfig, seed []byte, rulesDir string) *RoomManager {
+	return &RoomManager{rooms: make(map[string]*Room), config: config, seed: seed, rulesDir: rulesDir}
+}
+
+// Create starts a room with a new game and hub, each room with its own hand evaluator.
+// rules names a rule file, <rules>.json in the rules directory, or is empty for the server's ruleset.
+func (rm *RoomManager) Create(id string, rules string) (*Room, error) {
+	rm.mux.Lock()
+	defer rm.mux.Unlock()
+	return rm.create(id, rules)
+}
+
+func (rm *RoomManager) create(id string, rules string) (*Room, error) {
+	if !roomIdPattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid room id: %q", id)
+	}
+	if _, ok := rm.rooms[id]; ok {
+		return nil, fmt.Errorf("room already exists: %s", id)
+	}
+	config, err := rm.roomConfig(rules)
+	if err != nil {
+		return nil, err
+	}
+	handEvaluator, err := NewHandEvaluator(config)
+	if err != nil {
+		return nil, err
+	}
+	m := &MahjongPlayManager{}
+	m.Init(handEvaluator, config.Ruleset)
+	if rm.seed != nil {
+		m.SetSeed(rm.seed)
+	}
+	room := &Room{id: id, rules: rules, manager: m, hub: newHub()}
+	go room.hub.run()
+	go m.Run(room.hub.Broadcast)
+	rm.rooms[id] = room
+	logger.Info("room created", F("room", id), F("rules", rules))
+	return room, nil
+}
+
+// roomConfig is the evaluator config of a room with the ruleset loaded from its rule file.
+func (rm *RoomManager) roomConfig(rules string) (*HandEvaluatorConfig, error) {
+	if rules == "" {
+		return rm.config, nil
+	}
+	if rm.rulesDir == "" {
+		return nil, fmt.Errorf("rule files are not enabled: %s", rules)
+	}
+	if !roomIdPattern.MatchString(rules) {
+		return nil, fmt.Errorf("invalid rules name: %q", rules)
+	}
+	ruleset, err := LoadRuleset(filepath.Join(rm.rulesDir, rules + ".json"))
+	if err != nil {
+		return nil, fmt.Errorf("rules %s: %v", rules, err)
+	}
+	config := *rm.config
+	config.Ruleset = ruleset
+	return &config, nil
+}
+
+func (rm *RoomManager) Get(id string) (*Room, bool) {
+	rm.mux.Lock()
+	defer rm.mux.Unlock()
+	room, ok := rm.rooms[id]
+	return room, ok
+}
+
+// Destroy stops the hub of the room and forgets it.
+func (rm *RoomManager) Destroy(id string) error {
+	rm.mux.Lock()
+	defer rm.mux.Unlock()
+	return rm.destroy(id)
+}
+
+func (rm *RoomManager) destroy(id string) error {
+	room, ok := rm.rooms[id]
+	if !ok {
+		return fmt.Errorf("no room: %s", id)
+	}
+	room.manager.Stop()
+	close(room.hub.quit)
+	delete(rm.rooms, id)
+	logger.Info("room destroyed", F("room", id))
+	return nil
+}
+
+// Join gives the room for a new connection, creating it with rules if needed.
+// A room that has been joined is not destroyed until Leave.
+func (rm *RoomManager) Join(id string, rules string) (*Room, error) {
+	rm.mux.Lock()
+	defer rm.mux.Unlock()
+	room, ok := rm.rooms[id]
+	if !ok {
+		var err error
+		room, err = rm.create(id, rules)
+		if err != nil {
+			return nil, err
+		}
+	} else if rules != "" && rules != room.rules {
+		return nil, fmt.Errorf("room %s is played with other rules: %q", id, room.rules)
+	}
+	room.clients++
+	return room, nil
+}
+
+// Leave destroys the room when the last connection leaves.
+func (rm *RoomManager) Leave(room *Room) {
+	rm.mux.Lock()
+	defer rm.mux.Unlock()
+	room.clients--
+	if room.clients == 0 {
+		if current, ok := rm.rooms[room.id]; ok && current == room {
+			rm.destroy(room.id)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+const (
+	yakuSetPinfu = "pinfu"
+	yakuSetAny = "any"
+	gameLengthEast = "east"
+	gameLengthEastSouth = "eastSouth"
+	defaultRonTimeout = 10 * time.Second
+	defaultStartPoints = 25000
+	defaultReturnPoints = 30000
+	defaultHonbaPoints = 300
+)
+
+var defaultUma = []int{20, 10, -10, -20}
+
+// Ruleset is the rules a room is played with. Yaku are the yaku a win can count,
+// and a hand without any of them cannot win. MultipleRon lets every player who claims
+// a discard win it, otherwise only the nearest one after the discarder wins (atamahane).
+// RonTimeout is how long players who can win a discard have to answer, 0 for no limit.
+//
+// GameLength is east or eastSouth. The game ends after the fourth round of its last wind,
+// or a wind later when ExtendOnTie is set and every player has the same points, and
+// at once when EndOnBust is set and a player goes below 0. Uma is by order in thousands.
+// HonbaPoints is what a win gets for each honba. DeadWall keeps the last 14 tiles of the wall
+// from being drawn, otherwise every tile can be drawn. Tsumo lets the player in turn win
+// on the drawn tile. Riichi lets a player with closed tenpai hands declare riichi.
+// Furiten keeps a player who discarded or passed a winning tile from winning by ron.
+type Ruleset struct {
+	Yaku []string `json:"yaku"`
+	MultipleRon bool `json:"multipleRon"`
+	RonTimeout time.Duration `json:"ronTimeout"`
+	GameLength string `json:"gameLength"`
+	StartPoints int `json:"startPoints"`
+	ReturnPoints int `json:"returnPoints"`
+	Uma []int `json:"uma"`
+	HonbaPoints int `json:"honbaPoints"`
+	ExtendOnTie bool `json:"extendOnTie"`
+	EndOnBust bool `json:"endOnBust"`
+	DeadWall bool `json:"deadWall"`
+	Tsumo bool `json:"tsumo"`
+	Riichi bool `json:"riichi"`
+	Furiten bool `json:"furiten"`
+}
+
+// NewRuleset gives the ruleset of a yaku set: pinfu for pinfu-only or any for every registered yaku.
+// The other rules are the ones in the README.
+func NewRuleset(yakuSet string) (*Ruleset, error) {
+	switch yakuSet {
+	case yakuSetPinfu:
+		return newDefaultRuleset([]string{yakuPinfu}), nil
+	case yakuSetAny:
+		return newDefaultRuleset(YakuNames()), nil
+	}
+	return nil, fmt.Errorf("unknown yaku set: %s", yakuSet)
+}
+
+func newDefaultRuleset(yaku []string) *Ruleset {
+	uma := make([]int, len(defaultUma))
+	copy(uma, defaultUma)
+	return &Ruleset{yaku, false, defaultRonTimeout, gameLengthEast, defaultStartPoints, defaultReturnPoints, uma, defaultHonbaPoints, true, false, false, false, false, false}
+}
+
+// LoadRuleset reads a ruleset from a JSON file. Rules missing from the file are those of the pinfu yaku set.
+func LoadRuleset(path string) (*Ruleset, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ruleset := newDefaultRuleset([]string{yakuPinfu})
+	if err := json.Unmarshal(body, ruleset); err != nil {
+		return nil, err
+	}
+	if err := ruleset.Validate(); err != nil {
+		return nil, err
+	}
+	return ruleset, nil
+}
+
+type rulesetJSON Ruleset
+
+// MarshalJSON writes RonTimeout as a duration string such as 10s.
+func (r *Ruleset) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		RonTimeout string `json:"ronTimeout"`
+		*rulesetJSON
+	}{r.RonTimeout.String(), (*rulesetJSON)(r)})
+}
+
+func (r *Ruleset) UnmarshalJSON(data []byte) error {
+	aux := &struct {
+		RonTimeout string `json:"ronTimeout"`
+		*rulesetJSON
+	}{"", (*rulesetJSON)(r)}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	if aux.RonTimeout != "" {
+		timeout, err := time.ParseDuration(aux.RonTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid ron timeout: %v", err)
+		}
+		r.RonTimeout = timeout
+	}
+	return nil
+}
+
+func (r *Ruleset) Validate() error {
+	if len(r.Yaku) == 0 {
+		return fmt.Errorf("ruleset has no yaku")
+	}
+	for _, name := range r.Yaku {
+		if _, ok := FindYaku(name); !ok {
+			return fmt.Errorf("unknown yaku: %s", name)
+		}
+	}
+	if r.RonTimeout < 0 {
+		return fmt.Errorf("negative ron timeout: %s", r.RonTimeout)
+	}
+	if r.GameLength != gameLengthEast && r.GameLength != gameLengthEastSouth {
+		return fmt.Errorf("unknown game length: %s", r.GameLength)
+	}
+	if r.StartPoints <= 0 || r.StartPoints % pointRounding != 0 {
+		return fmt.Errorf("start points must be a positive multiple of %d: %d", pointRounding, r.StartPoints)
+	}
+	if r.ReturnPoints < r.StartPoints || r.ReturnPoints % umaPointUnit != 0 {
+		return fmt.Errorf("return points must be a multiple of %d from the start points: %d", umaPointUnit, r.ReturnPoints)
+	}
+	if len(r.Uma) != playerNumber {
+		return fmt.Errorf("uma must have %d orders: %v", playerNumber, r.Uma)
+	}
+	sum := 0
+	for _, u := range r.Uma {
+		sum += u
+	}
+	if sum != 0 {
+		return fmt.Errorf("uma must sum to 0: %v", r.Uma)
+	}
+	if r.HonbaPoints < 0 || r.HonbaPoints % (playerNumber - 1) != 0 {
+		return fmt.Errorf("honba points must be a non-negative multiple of %d: %d", playerNumber - 1, r.HonbaPoints)
+	}
+	return nil
+}
+
+// FinalWind is the wind of the last round of a game that does not go into extension.
+func (r *Ruleset) FinalWind() Wind {
+	if r.GameLength == gameLengthEastSouth {
+		return SOUTH
+	}
+	return EAST
+}
+
+func (r *Ruleset) IsPinfuOnly() bool {
+	return len(r.Yaku) == 1 && r.Yaku[0] == yakuPinfu
+}
+
+func (r *Ruleset) HasYaku(name string) bool {
+	return containsYaku(r.Yaku, name)
+}
+
+// EvaluateWin scores the hands plus the winning tile by the yaku of the ruleset,
+// taking the split that pays the most.
+func (r *Ruleset) EvaluateWin(hands []Tile, winTile Tile, situation WinSituation, playerWind Wind, roundWind Wind) *PinfuInfo {
+	best := &PinfuInfo{false, 0, 0, 0, nil, ""}
+	bestScore := NewScore(0, 0)
+	winYaku := r.winYaku(situation.IsTsumo)
+	for _, d := range Decompose(hands, winTile) {
+		w := &WinContext{d, situation, playerWind, roundWind}
+		han := 0
+		yaku := []string{}
+		for _, name := range winYaku {
+			y, ok := FindYaku(name)
+			if !ok {
+				continue
+			}
+			if h := y.Han(w); h > 0 {
+				han += h
+				yaku = append(yaku, name)
+			}
+		}
+		if han == 0 {
+			continue
+		}
+		score := NewScore(han, CalculateFu(d, situation.IsTsumo, playerWind, roundWind))
+		if score.BasePoints > bestScore.BasePoints || (score.BasePoints == bestScore.BasePoints && score.Han > bestScore.Han) {
+			bestScore = score
+			best = &PinfuInfo{containsYaku(yaku, yakuPinfu), score.RonPayment(playerWind == EAST, 0), score.Han, score.Fu, yaku, d.WaitShape()}
+		}
+	}
+	return best
+}
+
+// winYaku is the yaku a win can count. Every hand is closed, so a tsumo always has
+// menzen tsumo when the ruleset allows tsumo, and riichi and ippatsu count when it allows riichi,
+// even when its yaku are pinfu only.
+func (r *Ruleset) winYaku(isTsumo bool) []string {
+	yaku := r.Yaku
+	if isTsumo && r.Tsumo {
+		yaku = appendYaku(yaku, yakuMenzenTsumo)
+	}
+	if r.Riichi {
+		yaku = appendYaku(yaku, yakuRiichi, yakuIppatsu)
+	}
+	return yaku
+}
+
+func appendYaku(yaku []string, names ...string) []string {
+	for _, name := range names {
+		if !containsYaku(yaku, name) {
+			yaku = append(append([]string{}, yaku...), name)
+		}
+	}
+	return yaku
+}
+
+func containsYaku(yaku []string, name string) bool {
+	for _, y := range yaku {
+		if y == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRuleset(t *testing.T, yakuSet string) *Ruleset {
+	t.Helper()
+	r, err := NewRuleset(yakuSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestTsumoCountsMenzenTsumo(t *testing.T) {
+	hands := mustParseTiles(t, "234m456p789s23s55p")
+	winTile := mustParseTiles(t, "1s")[0]
+	tests := []struct {
+		yakuSet string
+		isTsumo bool
+		want string
+		han int
+	}{
+		{yakuSetPinfu, true, "pinfu menzen_tsumo", 2},
+		{yakuSetAny, true, "pinfu menzen_tsumo", 2},
+		{yakuSetPinfu, false, "pinfu", 1},
+	}
+	for _, test := range tests {
+		ruleset := testRuleset(t, test.yakuSet)
+		ruleset.Tsumo = true
+		info := ruleset.EvaluateWin(hands, winTile, WinSituation{IsTsumo: test.isTsumo}, SOUTH, EAST)
+		if got := strings.Join(info.Yaku, " "); got != test.want || info.Han != test.han {
+			t.Errorf("%s, tsumo %t: got %s %d han, want %s %d han", test.yakuSet, test.isTsumo, got, info.Han, test.want, test.han)
+		}
+	}
+}
+
+func TestRiichiCountsRiichiAndIppatsu(t *testing.T) {
+	hands := mustParseTiles(t, "23m456789p123s55s")
+	winTile := mustParseTiles(t, "1m")[0]
+	tests := []struct {
+		yakuSet string
+		isRiichi bool
+		want string
+	}{
+		{yakuSetPinfu, true, "pinfu riichi ippatsu"},
+		{yakuSetAny, true, "pinfu riichi ippatsu"},
+		{yakuSetPinfu, false, "pinfu"},
+	}
+	for _, test := range tests {
+		ruleset := testRuleset(t, test.yakuSet)
+		ruleset.Riichi = true
+		info := ruleset.EvaluateWin(hands, winTile, WinSituation{IsRiichi: test.isRiichi, IsIppatsu: test.isRiichi}, SOUTH, EAST)
+		if got := strings.Join(info.Yaku, " "); got != test.want {
+			t.Errorf("%s, riichi %t: got %s, want %s", test.yakuSet, test.isRiichi, got, test.want)
+		}
+	}
+}
+
+func testRuleDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func writeRuleFile(t *testing.T, dir string, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, "rules.json")
+	if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadTestManager(t *testing.T, dir string, body string) *MahjongPlayManager {
+	t.Helper()
+	ruleset, err := LoadRuleset(writeRuleFile(t, dir, body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	return m
+}
+
+func setPoints(m *MahjongPlayManager, points []int) {
+	for i, p := range m.playerInfos {
+		p.Point = points[i]
+	}
+}
+
+func TestLoadRuleset(t *testing.T) {
+	dir := testRuleDir(t)
+	defer os.RemoveAll(dir)
+	ruleset, err := LoadRuleset(writeRuleFile(t, dir, `{"ronTimeout":"15s","gameLength":"eastSouth","returnPoints":35000,"uma":[15,5,-5,-15],"honbaPoints":600,"endOnBust":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Ruleset{[]string{yakuPinfu}, false, 15 * time.Second, gameLengthEastSouth, defaultStartPoints, 35000, []int{15, 5, -5, -15}, 600, true, true, false, false, false, false}
+	if !reflect.DeepEqual(ruleset, want) {
+		t.Fatalf("got %+v, want %+v", ruleset, want)
+	}
+}
+
+func TestLoadRulesetInvalidFile(t *testing.T) {
+	dir := testRuleDir(t)
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not json", `{"gameLength":`},
+		{"wrong type", `{"startPoints":"25000"}`},
+		{"unknown yaku", `{"yaku":["kokushi"]}`},
+		{"no yaku", `{"yaku":[]}`},
+		{"invalid ron timeout", `{"ronTimeout":"ten seconds"}`},
+		{"negative ron timeout", `{"ronTimeout":"-1s"}`},
+		{"unknown game length", `{"gameLength":"north"}`},
+		{"start points not rounded", `{"startPoints":25050}`},
+		{"return points below start points", `{"returnPoints":20000}`},
+		{"uma for two players", `{"uma":[10,-10]}`},
+		{"uma not summing to 0", `{"uma":[20,10,-10,-10]}`},
+		{"honba points not split by 3", `{"honbaPoints":100}`},
+	}
+	for _, test := range tests {
+		if _, err := LoadRuleset(writeRuleFile(t, dir, test.body)); err == nil {
+			t.Errorf("%s: want an error for %s", test.name, test.body)
+		}
+	}
+	if _, err := LoadRuleset(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("want an error for a missing file")
+	}
+}
+
+func TestContinueGameByRuleFile(t *testing.T) {
+	dir := testRuleDir(t)
+	defer os.RemoveAll(dir)
+	tie := []int{25000, 25000, 25000, 25000}
+	lead := []int{35000, 25000, 25000, 15000}
+	bust := []int{50000, 35000, 16000, -1000}
+	tests := []struct {
+		body string
+		round Round
+		points []int
+		want bool
+	}{
+		{`{"gameLength":"east"}`, Round{EAST, 3, 0}, lead, true},
+		{`{"gameLength":"east"}`, Round{EAST, 4, 0}, lead, false},
+		{`{"gameLength":"east","extendOnTie":true}`, Round{EAST, 4, 0}, tie, true},
+		{`{"gameLength":"east","extendOnTie":false}`, Round{EAST, 4, 0}, tie, false},
+		{`{"gameLength":"east","extendOnTie":true}`, Round{SOUTH, 4, 0}, tie, false},
+		{`{"gameLength":"eastSouth"}`, Round{EAST, 4, 0}, lead, true},
+		{`{"gameLength":"eastSouth"}`, Round{SOUTH, 4, 0}, lead, false},
+		{`{"gameLength":"eastSouth","extendOnTie":true}`, Round{SOUTH, 4, 0}, tie, true},
+		{`{"endOnBust":true}`, Round{EAST, 1, 0}, bust, false},
+		{`{"endOnBust":false}`, Round{EAST, 1, 0}, bust, true},
+	}
+	for _, test := range tests {
+		m := loadTestManager(t, dir, test.body)
+		round := test.round
+		m.round = &round
+		setPoints(m, test.points)
+		if got := m.continueGame(); got != test.want {
+			t.Errorf("%s in %+v with %v: got %t, want %t", test.body, test.round, test.points, got, test.want)
+		}
+	}
+}
+
+func TestResultByRuleFile(t *testing.T) {
+	dir := testRuleDir(t)
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		body string
+		want []*Result
+	}{
+		{`{}`, []*Result{{50, 1}, {10, 2}, {-20, 3}, {-40, 4}}},
+		{`{"returnPoints":35000,"uma":[15,5,-5,-15]}`, []*Result{{60, 1}, {0, 2}, {-20, 3}, {-40, 4}}},
+		{`{"startPoints":30000,"returnPoints":30000,"uma":[30,10,-10,-30]}`, []*Result{{60, 1}, {10, 2}, {-20, 3}, {-50, 4}}},
+	}
+	for _, test := range tests {
+		m := loadTestManager(t, dir, test.body)
+		setPoints(m, []int{40000, 30000, 20000, 10000})
+		if got := m.CalculateResult(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.body, got, test.want)
+		}
+	}
+}
+
+func TestHonbaByRuleFile(t *testing.T) {
+	dir := testRuleDir(t)
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		body string
+		subRound int
+		want int
+	}{
+		{`{}`, 0, 1000},
+		{`{}`, 2, 1600},
+		{`{"honbaPoints":600}`, 2, 2200},
+		{`{"honbaPoints":0}`, 2, 1000},
+	}
+	for _, test := range tests {
+		m := loadTestManager(t, dir, test.body)
+		m.round.SubRound = test.subRound
+		m.playerIdInTurn = 0
+		m.playerInfos[1].PinfuInfo = &PinfuInfo{true, 1000, 1, 30, []string{yakuPinfu}, ""}
+		r := m.CalculateRonInfo(1)
+		if r[1].PointDiff != test.want || r[0].PointDiff != -test.want {
+			t.Errorf("%s with %d honba: got %d and %d, want %d", test.body, test.subRound, r[1].PointDiff, r[0].PointDiff, test.want)
+		}
+	}
+}
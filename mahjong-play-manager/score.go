@@ -0,0 +1,149 @@
+package main
+
+const (
+	fuBase = 20
+	fuClosedRon = 10
+	fuTsumo = 2
+	fuPinfuTsumo = 20
+	fuPinfuRon = 30
+	fuWait = 2
+	fuYakuhaiPair = 2
+	fuOpenTriplet = 2
+	fuRounding = 10
+	pointRounding = 100
+	hanMangan = 5
+	hanHaneman = 6
+	hanBaiman = 8
+	hanSanbaiman = 11
+	hanYakuman = 13
+	basePointsMangan = 2000
+)
+
+type Limit string
+
+const (
+	LimitNone Limit = ""
+	LimitMangan Limit = "mangan"
+	LimitHaneman Limit = "haneman"
+	LimitBaiman Limit = "baiman"
+	LimitSanbaiman Limit = "sanbaiman"
+	LimitYakuman Limit = "yakuman"
+)
+
+var limitBasePoints = map[Limit]int{
+	LimitMangan: basePointsMangan,
+	LimitHaneman: basePointsMangan*3/2,
+	LimitBaiman: basePointsMangan*2,
+	LimitSanbaiman: basePointsMangan*3,
+	LimitYakuman: basePointsMangan*4,
+}
+
+// Score is the value of a win before it is paid, from the standard table without kiriage mangan.
+type Score struct {
+	Han int `json:"han"`
+	Fu int `json:"fu"`
+	Limit Limit `json:"limit,omitempty"`
+	BasePoints int `json:"basePoints"`
+}
+
+func NewScore(han int, fu int) *Score {
+	s := &Score{Han: han, Fu: fu}
+	switch {
+	case han >= hanYakuman:
+		s.Limit = LimitYakuman
+	case han >= hanSanbaiman:
+		s.Limit = LimitSanbaiman
+	case han >= hanBaiman:
+		s.Limit = LimitBaiman
+	case han >= hanHaneman:
+		s.Limit = LimitHaneman
+	case han >= hanMangan:
+		s.Limit = LimitMangan
+	case han > 0:
+		s.BasePoints = fu << uint(han + 2)
+		if s.BasePoints > basePointsMangan {
+			s.Limit = LimitMangan
+		}
+	}
+	if s.Limit != LimitNone {
+		s.BasePoints = limitBasePoints[s.Limit]
+	}
+	return s
+}
+
+// RonPayment is what the discarder pays, with honbaPoints for the honba.
+func (s *Score) RonPayment(isDealer bool, honbaPoints int) int {
+	if s.BasePoints == 0 {
+		return 0
+	}
+	if isDealer {
+		return roundUpPoint(s.BasePoints*6) + honbaPoints
+	}
+	return roundUpPoint(s.BasePoints*4) + honbaPoints
+}
+
+// TsumoPayments is what the dealer and each non-dealer pays for a tsumo, the honbaPoints
+// shared among the payers. The dealer payment is unused when the dealer is the winner.
+func (s *Score) TsumoPayments(isDealer bool, honbaPoints int) (int, int) {
+	if s.BasePoints == 0 {
+		return 0, 0
+	}
+	honbaPayment := honbaPoints/(playerNumber - 1)
+	if isDealer {
+		payment := roundUpPoint(s.BasePoints*2) + honbaPayment
+		return payment, payment
+	}
+	return roundUpPoint(s.BasePoints*2) + honbaPayment, roundUpPoint(s.BasePoints) + honbaPayment
+}
+
+// CalculateFu is the fu of a closed hand read as the split.
+func CalculateFu(d *Decomposition, isTsumo bool, playerWind Wind, roundWind Wind) int {
+	if d.IsPinfu(playerWind, roundWind) {
+		if isTsumo {
+			return fuPinfuTsumo
+		}
+		return fuPinfuRon
+	}
+	fu := fuBase
+	if isTsumo {
+		fu += fuTsumo
+	} else {
+		fu += fuClosedRon
+	}
+	for i, m := range d.Melds {
+		if m.Type != MeldTriplet {
+			continue
+		}
+		meldFu := fuOpenTriplet
+		if isTsumo || i != d.WinningMeld {
+			meldFu *= 2
+		}
+		if isTerminalOrHonorKind(m.Tiles[0].Kind()) {
+			meldFu *= 2
+		}
+		fu += meldFu
+	}
+	pairKind := d.Pair[0].Kind()
+	if pairKind >= tileKindDragonStart {
+		fu += fuYakuhaiPair
+	}
+	if pairKind == windKind(playerWind) {
+		fu += fuYakuhaiPair
+	}
+	if pairKind == windKind(roundWind) {
+		fu += fuYakuhaiPair
+	}
+	switch d.WaitShape() {
+	case WaitKanchan, WaitPenchan, WaitTanki:
+		fu += fuWait
+	}
+	return roundUp(fu, fuRounding)
+}
+
+func roundUpPoint(point int) int {
+	return roundUp(point, pointRounding)
+}
+
+func roundUp(value int, unit int) int {
+	return (value + unit - 1)/unit*unit
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestScorePayments(t *testing.T) {
+	tests := []struct {
+		han int
+		fu int
+		limit Limit
+		ron int
+		dealerRon int
+		tsumoDealer int
+		tsumoNonDealer int
+		dealerTsumo int
+	}{
+		{1, 30, LimitNone, 1000, 1500, 500, 300, 500},
+		{1, 40, LimitNone, 1300, 2000, 700, 400, 700},
+		{2, 20, LimitNone, 1300, 2000, 700, 400, 700},
+		{2, 25, LimitNone, 1600, 2400, 800, 400, 800},
+		{2, 30, LimitNone, 2000, 2900, 1000, 500, 1000},
+		{3, 30, LimitNone, 3900, 5800, 2000, 1000, 2000},
+		{3, 60, LimitNone, 7700, 11600, 3900, 2000, 3900},
+		{4, 30, LimitNone, 7700, 11600, 3900, 2000, 3900},
+		{3, 70, LimitMangan, 8000, 12000, 4000, 2000, 4000},
+		{4, 40, LimitMangan, 8000, 12000, 4000, 2000, 4000},
+		{5, 30, LimitMangan, 8000, 12000, 4000, 2000, 4000},
+		{6, 30, LimitHaneman, 12000, 18000, 6000, 3000, 6000},
+		{7, 30, LimitHaneman, 12000, 18000, 6000, 3000, 6000},
+		{8, 30, LimitBaiman, 16000, 24000, 8000, 4000, 8000},
+		{10, 30, LimitBaiman, 16000, 24000, 8000, 4000, 8000},
+		{11, 30, LimitSanbaiman, 24000, 36000, 12000, 6000, 12000},
+		{12, 30, LimitSanbaiman, 24000, 36000, 12000, 6000, 12000},
+		{13, 30, LimitYakuman, 32000, 48000, 16000, 8000, 16000},
+	}
+	for _, test := range tests {
+		s := NewScore(test.han, test.fu)
+		if s.Limit != test.limit {
+			t.Errorf("%dhan %dfu: got limit %q, want %q", test.han, test.fu, s.Limit, test.limit)
+		}
+		if got := s.RonPayment(false, 0); got != test.ron {
+			t.Errorf("%dhan %dfu: got ron %d, want %d", test.han, test.fu, got, test.ron)
+		}
+		if got := s.RonPayment(true, 0); got != test.dealerRon {
+			t.Errorf("%dhan %dfu: got dealer ron %d, want %d", test.han, test.fu, got, test.dealerRon)
+		}
+		if dealer, nonDealer := s.TsumoPayments(false, 0); dealer != test.tsumoDealer || nonDealer != test.tsumoNonDealer {
+			t.Errorf("%dhan %dfu: got tsumo %d/%d, want %d/%d", test.han, test.fu, nonDealer, dealer, test.tsumoNonDealer, test.tsumoDealer)
+		}
+		if dealer, nonDealer := s.TsumoPayments(true, 0); dealer != test.dealerTsumo || nonDealer != test.dealerTsumo {
+			t.Errorf("%dhan %dfu: got dealer tsumo %d/%d, want %d all", test.han, test.fu, dealer, nonDealer, test.dealerTsumo)
+		}
+	}
+}
+
+func TestScoreWithoutHan(t *testing.T) {
+	s := NewScore(0, 30)
+	dealer, nonDealer := s.TsumoPayments(false, defaultHonbaPoints)
+	if s.RonPayment(false, defaultHonbaPoints) != 0 || dealer != 0 || nonDealer != 0 {
+		t.Fatalf("got a payment for %+v", s)
+	}
+}
+
+func TestScoreHonba(t *testing.T) {
+	s := NewScore(1, 30)
+	honbaPoints := 2*defaultHonbaPoints
+	if got := s.RonPayment(false, honbaPoints); got != 1600 {
+		t.Errorf("got ron %d, want 1600", got)
+	}
+	if got := s.RonPayment(true, honbaPoints); got != 2100 {
+		t.Errorf("got dealer ron %d, want 2100", got)
+	}
+	if dealer, nonDealer := s.TsumoPayments(false, honbaPoints); dealer != 700 || nonDealer != 500 {
+		t.Errorf("got tsumo %d/%d, want 500/700", nonDealer, dealer)
+	}
+	if dealer, _ := s.TsumoPayments(true, honbaPoints); dealer != 700 {
+		t.Errorf("got dealer tsumo %d, want 700 all", dealer)
+	}
+}
+
+func TestCalculateFu(t *testing.T) {
+	tests := []struct {
+		hands string
+		winTile string
+		isTsumo bool
+		want int
+	}{
+		{"23m456789p123s55s", "1m", false, fuPinfuRon},
+		{"23m456789p123s55s", "1m", true, fuPinfuTsumo},
+		// kanchan
+		{"13m456789p123s55s", "2m", false, 40},
+		// kanchan and a double east pair
+		{"13m456789p123s11z", "2m", false, 40},
+		// a concealed terminal triplet and tanki
+		{"111m456789p123s5s", "5s", false, 40},
+		// a triplet completed by ron counts as open
+		{"11m456789p123s55s", "1m", false, 40},
+		{"11m456789p123s55s", "1m", true, 30},
+		{"22m456789p123s55s", "2m", false, 40},
+		{"22m456789p123s55s", "2m", true, 30},
+	}
+	for _, test := range tests {
+		best := 0
+		for _, d := range Decompose(mustParseTiles(t, test.hands), mustParseTiles(t, test.winTile)[0]) {
+			if fu := CalculateFu(d, test.isTsumo, EAST, EAST); fu > best {
+				best = fu
+			}
+		}
+		if best != test.want {
+			t.Errorf("%s+%s tsumo=%v: got %dfu, want %d", test.hands, test.winTile, test.isTsumo, best, test.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+// SeatInfo tells a connection its seat, or for a spectator its place in the waitlist from 1.
+type SeatInfo struct {
+	PlayerId int `json:"playerId"`
+	WaitingPosition int `json:"waitingPosition"`
+}
+
+type directMessage struct {
+	client *Client
+	message []byte
+}
+
+// TakeSeat seats the client in the first free seat, or adds it to the waitlist as a spectator
+// when all seats are taken. It reports whether the table has just filled for the first time,
+// and whether the client took over a seat of a game that has already started.
+func (h *Hub) TakeSeat(client *Client) (bool, bool) {
+	h.seatMux.Lock()
+	defer h.seatMux.Unlock()
+	for playerId, c := range h.seats {
+		if c == nil {
+			h.seat(client, playerId)
+			if h.started {
+				return false, true
+			}
+			if h.isFull() {
+				h.started = true
+				return true, false
+			}
+			return false, false
+		}
+	}
+	h.spectators = append(h.spectators, client)
+	client.setPlayerId(playerIdNone)
+	logger.Info("spectator waiting", F("position", len(h.spectators)))
+	h.SendTo(client, (&SendMessage{"spectator", &SeatInfo{playerIdNone, len(h.spectators)}}).ToBytes())
+	return false, false
+}
+
+// LeaveSeat frees the seat of a leaving player for the first spectator in the waitlist,
+// or takes a leaving spectator off the waitlist. It returns the seat a spectator was moved to,
+// or playerIdNone.
+func (h *Hub) LeaveSeat(client *Client) int {
+	h.seatMux.Lock()
+	defer h.seatMux.Unlock()
+	playerId := client.PlayerId()
+	if playerId == playerIdNone {
+		for i, c := range h.spectators {
+			if c == client {
+				h.spectators = append(h.spectators[:i], h.spectators[i + 1:]...)
+				break
+			}
+		}
+		h.sendWaitingPositions()
+		return playerIdNone
+	}
+	h.seats[playerId] = nil
+	logger.Info("seat freed", F("playerId", playerId))
+	if len(h.spectators) == 0 {
+		return playerIdNone
+	}
+	next := h.spectators[0]
+	h.spectators = h.spectators[1:]
+	h.seat(next, playerId)
+	h.sendWaitingPositions()
+	return playerId
+}
+
+func (h *Hub) seat(client *Client, playerId int) {
+	h.seats[playerId] = client
+	client.setPlayerId(playerId)
+	logger.Info("seat taken", F("playerId", playerId))
+	h.SendTo(client, (&SendMessage{"seat", &SeatInfo{playerId, 0}}).ToBytes())
+}
+
+func (h *Hub) sendWaitingPositions() {
+	for i, c := range h.spectators {
+		h.SendTo(c, (&SendMessage{"spectator", &SeatInfo{playerIdNone, i + 1}}).ToBytes())
+	}
+}
+
+func (h *Hub) isFull() bool {
+	for _, c := range h.seats {
+		if c == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// SendTo sends a message to one client only, through the hub so it is not sent after the client is gone.
+func (h *Hub) SendTo(client *Client, message []byte) {
+	select {
+	case h.direct <- &directMessage{client, message}:
+	case <-h.quit:
+	}
+}
+
+func (c *Client) PlayerId() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.playerId
+}
+
+func (c *Client) setPlayerId(playerId int) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.playerId = playerId
+}
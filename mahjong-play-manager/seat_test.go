@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(h *Hub) *Client {
+	c := &Client{hub: h, send: make(chan []byte, 256), playerId: playerIdNone}
+	h.Register(c)
+	return c
+}
+
+// receiveType reads the messages of the client until one of the type arrives.
+func receiveType(t *testing.T, c *Client, messageType string) []byte {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case b := <-c.send:
+			s := &SendMessage{}
+			if err := json.Unmarshal(b, s); err != nil {
+				t.Fatal(err)
+			}
+			if s.Type == messageType {
+				return b
+			}
+		case <-timeout:
+			t.Fatalf("no %s message", messageType)
+		}
+	}
+}
+
+func TestTakeAndLeaveSeat(t *testing.T) {
+	h := newHub()
+	go h.run()
+	defer close(h.quit)
+	clients := []*Client{}
+	filled := 0
+	for i := 0; i < playerNumber + 2; i++ {
+		c := newTestClient(h)
+		if start, _ := h.TakeSeat(c); start {
+			filled++
+		}
+		clients = append(clients, c)
+	}
+	if filled != 1 {
+		t.Fatalf("the table filled %d times", filled)
+	}
+	for i, c := range clients {
+		want := i
+		if i >= playerNumber {
+			want = playerIdNone
+		}
+		if c.PlayerId() != want {
+			t.Errorf("client %d: got seat %d, want %d", i, c.PlayerId(), want)
+		}
+	}
+
+	if playerId := h.LeaveSeat(clients[2]); playerId != 2 {
+		t.Fatalf("got %d, want the spectator moved to seat 2", playerId)
+	}
+	h.Unregister(clients[2])
+	if clients[4].PlayerId() != 2 || clients[5].PlayerId() != playerIdNone {
+		t.Fatalf("got seats %d and %d", clients[4].PlayerId(), clients[5].PlayerId())
+	}
+	spectator := receiveType(t, clients[5], "spectator")
+	if !strings.Contains(string(spectator), `"waitingPosition":2`) {
+		t.Fatalf("got %s first", spectator)
+	}
+	spectator = receiveType(t, clients[5], "spectator")
+	if !strings.Contains(string(spectator), `"waitingPosition":1`) {
+		t.Fatalf("got %s after the promotion", spectator)
+	}
+	if playerId := h.LeaveSeat(clients[5]); playerId != playerIdNone {
+		t.Fatalf("got %d for a leaving spectator", playerId)
+	}
+
+	c := newTestClient(h)
+	if start, takeover := h.TakeSeat(c); start || takeover || c.PlayerId() != playerIdNone {
+		t.Fatalf("got seat %d, want a spectator without starting again", c.PlayerId())
+	}
+}
+
+// A spectator moved to a seat during a hand gets the hands of the seat and the rivers,
+// although nothing changed for the seat since its last message.
+func TestPromotedSpectatorGetsSeatState(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	m := &MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	h := newHub()
+	go h.run()
+	defer close(h.quit)
+	go m.Run(h.Broadcast)
+	defer m.Stop()
+
+	clients := []*Client{}
+	for i := 0; i < playerNumber + 1; i++ {
+		c := newTestClient(h)
+		h.TakeSeat(c)
+		clients = append(clients, c)
+	}
+	if err := m.Submit(0, &Operator{"start", tileIdNone}); err != nil {
+		t.Fatal(err)
+	}
+	discarder := m.playerIdInTurn
+	if err := m.Submit(discarder, &Operator{"discard", tileIdNone}); err != nil {
+		t.Fatal(err)
+	}
+	leaving := (discarder + 1) % playerNumber
+	if playerId := h.LeaveSeat(clients[leaving]); playerId != leaving {
+		t.Fatalf("got %d, want %d", playerId, leaving)
+	}
+	m.Resend(leaving)
+
+	promoted := clients[playerNumber]
+	b := receiveType(t, promoted, "seatState")
+	state := struct {
+		Values struct {
+			PlayerInfo *PlayerInfo `json:"playerInfo"`
+			Rivers [][]Tile `json:"rivers"`
+		} `json:"values"`
+	}{}
+	if err := json.Unmarshal(b, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Values.PlayerInfo == nil || state.Values.PlayerInfo.PlayerId != leaving || len(state.Values.PlayerInfo.Hands) != tileInHandNumber {
+		t.Fatalf("got %s", b)
+	}
+	// the discarder sits on the left of the seat
+	if river := state.Values.Rivers[playerNumber - 1]; len(river) != 1 || river[0] != m.playerInfos[discarder].River[0] {
+		t.Fatalf("got rivers %v", state.Values.Rivers)
+	}
+}
+
+// A new connection that takes a seat freed during a game, with nobody waiting for it,
+// gets the state of the seat like a promoted spectator.
+func TestNewClientTakingFreedSeatGetsSeatState(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	m := &MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	room := &Room{id: "seat", manager: m, hub: newHub()}
+	go room.hub.run()
+	defer close(room.hub.quit)
+	go m.Run(room.hub.Broadcast)
+	defer m.Stop()
+
+	clients := []*Client{}
+	for i := 0; i < playerNumber; i++ {
+		c := newTestClient(room.hub)
+		room.Seat(c)
+		clients = append(clients, c)
+	}
+	receiveType(t, clients[0], "start")
+	leaving := (m.playerIdInTurn + 1) % playerNumber
+	if playerId := room.hub.LeaveSeat(clients[leaving]); playerId != playerIdNone {
+		t.Fatalf("got %d, want no spectator moved", playerId)
+	}
+	room.hub.Unregister(clients[leaving])
+
+	c := newTestClient(room.hub)
+	room.Seat(c)
+	if c.PlayerId() != leaving {
+		t.Fatalf("got seat %d, want %d", c.PlayerId(), leaving)
+	}
+	b := receiveType(t, c, "seatState")
+	if !strings.Contains(string(b), fmt.Sprintf(`"playerId":%d`, leaving)) {
+		t.Fatalf("got %s", b)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import "fmt"
+
+const (
+	kokushiKindNumber = 13
+	chiitoitsuPairNumber = 7
+)
+
+// UkeireTile is a tile kind that advances the hands and how many of it are still unseen.
+type UkeireTile struct {
+	Tile Tile `json:"tile"`
+	Remaining int `json:"remaining"`
+}
+
+// DiscardCandidate is the shanten and ukeire left after discarding Tile from 14 tiles.
+type DiscardCandidate struct {
+	Tile Tile `json:"tile"`
+	Shanten int `json:"shanten"`
+	Ukeire []*UkeireTile `json:"ukeire"`
+	Remaining int `json:"remaining"`
+}
+
+// Shanten is the number of tiles the hands are away from tenpai for 13 tiles,
+// or from winning for 14 tiles, where -1 means the 14 tiles already win.
+// It is the smallest of the standard hand, chiitoitsu and kokushi.
+func Shanten(tiles []Tile) int {
+	return shantenOfCounts(tileKindCounts(tiles))
+}
+
+// Ukeire lists the kinds that lower the shanten of 13 tiles. The remaining count
+// takes off the copies in the hands and in visible, which is what one seat has seen.
+func Ukeire(hands []Tile, visible []Tile) ([]*UkeireTile, error) {
+	if len(hands)%3 != 1 {
+		return nil, fmt.Errorf("ukeire needs 3n+1 tiles, got %d", len(hands))
+	}
+	counts := tileKindCounts(hands)
+	seen := tileKindCounts(visible)
+	shanten := Shanten(hands)
+	ukeire := []*UkeireTile{}
+	for kind := range counts {
+		if counts[kind] == tileCopyNumber {
+			continue
+		}
+		counts[kind]++
+		if shantenOfCounts(counts) < shanten {
+			remaining := tileCopyNumber - counts[kind] + 1 - seen[kind]
+			if remaining < 0 {
+				remaining = 0
+			}
+			ukeire = append(ukeire, &UkeireTile{NewTile(kind, 0), remaining})
+		}
+		counts[kind]--
+	}
+	return ukeire, nil
+}
+
+// DiscardCandidates lists, for each kind in 14 tiles, the shanten and ukeire after discarding it.
+func DiscardCandidates(tiles []Tile, visible []Tile) ([]*DiscardCandidate, error) {
+	if len(tiles)%3 != 2 {
+		return nil, fmt.Errorf("discard candidates need 3n+2 tiles, got %d", len(tiles))
+	}
+	candidates := []*DiscardCandidate{}
+	discarded := make([]bool, tileKindNumber)
+	for i, t := range tiles {
+		if discarded[t.Kind()] {
+			continue
+		}
+		discarded[t.Kind()] = true
+		hands := make([]Tile, 0, len(tiles) - 1)
+		hands = append(hands, tiles[:i]...)
+		hands = append(hands, tiles[i + 1:]...)
+		ukeire, err := Ukeire(hands, append(append([]Tile{}, visible...), t))
+		if err != nil {
+			return nil, err
+		}
+		c := &DiscardCandidate{t, Shanten(hands), ukeire, 0}
+		for _, u := range ukeire {
+			c.Remaining += u.Remaining
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, nil
+}
+
+func shantenOfCounts(counts []int) int {
+	shanten := standardShanten(counts)
+	if s := chiitoitsuShanten(counts); s < shanten {
+		shanten = s
+	}
+	if s := kokushiShanten(counts); s < shanten {
+		shanten = s
+	}
+	return shanten
+}
+
+func standardShanten(counts []int) int {
+	tileNumber := 0
+	for _, c := range counts {
+		tileNumber += c
+	}
+	meldTarget := tileNumber/3
+	best := searchShanten(counts, 0, 0, 0, meldTarget)
+	for kind := range counts {
+		if counts[kind] >= 2 {
+			counts[kind] -= 2
+			if s := searchShanten(counts, 0, 0, 0, meldTarget) - 1; s < best {
+				best = s
+			}
+			counts[kind] += 2
+		}
+	}
+	return best
+}
+
+// searchShanten takes melds first and then partial melds out of the counts from kind on.
+// Partial melds beyond what can still become melds do not count.
+func searchShanten(counts []int, kind int, melds int, partials int, meldTarget int) int {
+	for kind < tileKindNumber && counts[kind] == 0 {
+		kind++
+	}
+	if kind == tileKindNumber {
+		if melds + partials > meldTarget {
+			partials = meldTarget - melds
+		}
+		return meldTarget*2 - melds*2 - partials
+	}
+
+	counts[kind]--
+	best := searchShanten(counts, kind, melds, partials, meldTarget)
+	counts[kind]++
+	if counts[kind] >= 3 {
+		counts[kind] -= 3
+		best = minShanten(best, searchShanten(counts, kind, melds + 1, partials, meldTarget))
+		counts[kind] += 3
+	}
+	isSuited := kind < tileKindHonorStart
+	rank := kind%tileInSuitNumber
+	if isSuited && rank <= tileInSuitNumber - 3 && counts[kind + 1] > 0 && counts[kind + 2] > 0 {
+		counts[kind]--
+		counts[kind + 1]--
+		counts[kind + 2]--
+		best = minShanten(best, searchShanten(counts, kind, melds + 1, partials, meldTarget))
+		counts[kind]++
+		counts[kind + 1]++
+		counts[kind + 2]++
+	}
+	if counts[kind] >= 2 {
+		counts[kind] -= 2
+		best = minShanten(best, searchShanten(counts, kind, melds, partials + 1, meldTarget))
+		counts[kind] += 2
+	}
+	for gap := 1; gap <= 2; gap++ {
+		if isSuited && rank + gap < tileInSuitNumber && counts[kind + gap] > 0 {
+			counts[kind]--
+			counts[kind + gap]--
+			best = minShanten(best, searchShanten(counts, kind, melds, partials + 1, meldTarget))
+			counts[kind]++
+			counts[kind + gap]++
+		}
+	}
+	return best
+}
+
+func minShanten(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func chiitoitsuShanten(counts []int) int {
+	pairs := 0
+	kinds := 0
+	for _, c := range counts {
+		if c >= 2 {
+			pairs++
+		}
+		if c > 0 {
+			kinds++
+		}
+	}
+	shanten := chiitoitsuPairNumber - 1 - pairs
+	if kinds < chiitoitsuPairNumber {
+		shanten += chiitoitsuPairNumber - kinds
+	}
+	return shanten
+}
+
+func kokushiShanten(counts []int) int {
+	kinds := 0
+	hasPair := false
+	for kind, c := range counts {
+		if !isTerminalOrHonorKind(kind) || c == 0 {
+			continue
+		}
+		kinds++
+		if c >= 2 {
+			hasPair = true
+		}
+	}
+	shanten := kokushiKindNumber - kinds
+	if hasPair {
+		shanten--
+	}
+	return shanten
+}
+
+func isTerminalOrHonorKind(kind int) bool {
+	return kind >= tileKindHonorStart || kind%tileInSuitNumber == 0 || kind%tileInSuitNumber == tileInSuitNumber - 1
+}
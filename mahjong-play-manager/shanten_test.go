@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestShanten(t *testing.T) {
+	tests := []struct {
+		hands string
+		want int
+	}{
+		// standard, 13 tiles
+		{"123456789m1234p", 0},
+		{"123456789m12p1z", 1},
+		{"123m456p789s1357z", 2},
+		{"147m258p369s1234z", 6},
+		// standard, 14 tiles
+		{"123456789m12344p", -1},
+		{"123456789m1245p", 1},
+		{"123456789m12p15z", 1},
+		// chiitoitsu
+		{"1122m3344p5566s7z", 0},
+		{"1122m3344p5566s77z", -1},
+		{"1122m3344p5s12367z", 2},
+		{"1122m3344p55s1234z", 1},
+		// kokushi
+		{"19m19p19s1234567z", 0},
+		{"19m19p19s12345677z", -1},
+		{"19m19p19s1234566z", 0},
+		{"258m19m19p19s1234z", 3},
+		{"159m19p19s123456z", 1},
+	}
+	for _, test := range tests {
+		if got := Shanten(mustParseTiles(t, test.hands)); got != test.want {
+			t.Errorf("%s: got %d, want %d", test.hands, got, test.want)
+		}
+	}
+}
+
+// A hand is tenpai exactly when some kind completes it.
+func TestShantenTenpaiMatchesWinningKinds(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 300; i++ {
+		hands := randomTenpaiHands(r)
+		if Shanten(hands) != 0 {
+			t.Fatalf("%s: got shanten %d for a tenpai hand", FormatTiles(hands), Shanten(hands))
+		}
+		perm := r.Perm(tileInMountNumber)
+		tiles := make([]Tile, tileInHandNumber)
+		for j := range tiles {
+			tiles[j] = Tile(perm[j])
+		}
+		if isTenpai, hasWaits := standardShanten(tileKindCounts(tiles)) == 0, len(winningKinds(tiles)) > 0; isTenpai != hasWaits {
+			t.Fatalf("%s: standard shanten and winning kinds disagree", FormatTiles(tiles))
+		}
+	}
+}
+
+func TestUkeire(t *testing.T) {
+	hands := mustParseTiles(t, "23m456789p123s11z")
+	ukeire, err := Ukeire(hands, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatUkeire(ukeire); got != "1m:4 4m:4" {
+		t.Fatalf("got %s", got)
+	}
+
+	// the copies the seat has seen and the copies in the hands are not remaining
+	ukeire, err = Ukeire(hands, mustParseTiles(t, "11m4m"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatUkeire(ukeire); got != "1m:2 4m:3" {
+		t.Fatalf("got %s", got)
+	}
+	ukeire, err = Ukeire(mustParseTiles(t, "11m456789p123s11z"), mustParseTiles(t, "1z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatUkeire(ukeire); got != "1m:2 1z:1" {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func formatUkeire(ukeire []*UkeireTile) string {
+	strs := []string{}
+	for _, u := range ukeire {
+		strs = append(strs, fmt.Sprintf("%s:%d", u.Tile, u.Remaining))
+	}
+	return strings.Join(strs, " ")
+}
+
+func TestUkeireNeeds3nPlus1Tiles(t *testing.T) {
+	if _, err := Ukeire(mustParseTiles(t, "23m456789p123s11z5z"), nil); err == nil {
+		t.Fatal("want an error for 14 tiles")
+	}
+}
+
+func TestDiscardCandidates(t *testing.T) {
+	candidates, err := DiscardCandidates(mustParseTiles(t, "23m456789p123s11z5z"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range candidates {
+		if c.Tile.Kind() == mustParseTiles(t, "5z")[0].Kind() && c.Shanten != 0 {
+			t.Fatalf("discarding 5z: got shanten %d, want tenpai", c.Shanten)
+		}
+		if c.Shanten < 0 {
+			t.Fatalf("got %+v", c)
+		}
+	}
+}
+
+func TestManagerUkeireSubtractsRivers(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	m := MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	m.playerInfos[0].Hands = mustParseTiles(t, "23m456789p123s11z")
+	m.playerInfos[1].River = mustParseTiles(t, "1m")
+	m.playerInfos[2].River = mustParseTiles(t, "11m")[1:]
+	m.playerInfos[3].River = mustParseTiles(t, "4m")
+	ukeire, err := m.Ukeire(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatUkeire(ukeire); got != "1m:2 4m:3" {
+		t.Fatalf("got %s", got)
+	}
+}
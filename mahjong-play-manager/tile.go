@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	tileCopyNumber = 4
+	TileNone Tile = tileIdNone
+)
+
+// Tile is one of the 136 tiles. Tiles of the same kind are numbered consecutively,
+// so 0-3 are the four 1m, 4-7 the four 2m and 132-135 the four chun.
+type Tile int
+
+type Suit int
+
+const (
+	SuitMan Suit = iota
+	SuitPin
+	SuitSou
+	SuitHonor
+)
+
+var suitLetters = [...]string{"m", "p", "s", "z"}
+
+func NewTile(kind int, copyIndex int) Tile {
+	return Tile(kind*tileCopyNumber + copyIndex)
+}
+
+// Kind is the index of the tile among the 34 kinds.
+func (t Tile) Kind() int {
+	return int(t)/tileCopyNumber
+}
+
+func (t Tile) Copy() int {
+	return int(t)%tileCopyNumber
+}
+
+func (t Tile) Suit() Suit {
+	return Suit(t.Kind()/tileInSuitNumber)
+}
+
+// Rank is 1-9 for suited tiles and 1-7 for honors in the order east, south, west, north, haku, hatsu, chun.
+func (t Tile) Rank() int {
+	return t.Kind()%tileInSuitNumber + 1
+}
+
+func (t Tile) IsValid() bool {
+	return t >= 0 && t < tileInMountNumber
+}
+
+func (t Tile) String() string {
+	if !t.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d%s", t.Rank(), t.Suit())
+}
+
+func (s Suit) String() string {
+	return suitLetters[s]
+}
+
+// ParseTiles parses MPSZ notation such as 123m456p789s11z.
+// Each tile takes the lowest copy of its kind that is not used yet.
+func ParseTiles(notation string) ([]Tile, error) {
+	tiles := []Tile{}
+	used := make([]int, tileKindNumber)
+	ranks := []int{}
+	for _, c := range notation {
+		switch {
+		case c >= '1' && c <= '9':
+			ranks = append(ranks, int(c - '0'))
+		case strings.ContainsRune("mpsz", c):
+			if len(ranks) == 0 {
+				return nil, fmt.Errorf("no ranks before %c in %s", c, notation)
+			}
+			suit := Suit(strings.IndexRune("mpsz", c))
+			for _, rank := range ranks {
+				if suit == SuitHonor && rank > tileKindNumber - tileKindHonorStart {
+					return nil, fmt.Errorf("invalid honor %d%c in %s", rank, c, notation)
+				}
+				kind := int(suit)*tileInSuitNumber + rank - 1
+				if used[kind] == tileCopyNumber {
+					return nil, fmt.Errorf("more than %d of %d%c in %s", tileCopyNumber, rank, c, notation)
+				}
+				tiles = append(tiles, NewTile(kind, used[kind]))
+				used[kind]++
+			}
+			ranks = ranks[:0]
+		default:
+			return nil, fmt.Errorf("unexpected %c in %s", c, notation)
+		}
+	}
+	if len(ranks) > 0 {
+		return nil, fmt.Errorf("no suit after the last ranks in %s", notation)
+	}
+	return tiles, nil
+}
+
+// FormatTiles writes the tiles in MPSZ notation in the given order.
+func FormatTiles(tiles []Tile) string {
+	var b strings.Builder
+	for i, t := range tiles {
+		if !t.IsValid() {
+			continue
+		}
+		b.WriteString(fmt.Sprint(t.Rank()))
+		if i == len(tiles) - 1 || !tiles[i + 1].IsValid() || tiles[i + 1].Suit() != t.Suit() {
+			b.WriteString(t.Suit().String())
+		}
+	}
+	return b.String()
+}
+
+func SortTiles(tiles []Tile) {
+	sort.Slice(tiles, func(i, j int) bool {
+		return tiles[i] < tiles[j]
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+)
+
+func mustParseTiles(t *testing.T, notation string) []Tile {
+	t.Helper()
+	tiles, err := ParseTiles(notation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tiles
+}
+
+func TestParseTiles(t *testing.T) {
+	tiles := mustParseTiles(t, "1123m5z")
+	want := []Tile{0, 1, 4, 8, 124}
+	if len(tiles) != len(want) {
+		t.Fatalf("got %v, want %v", tiles, want)
+	}
+	for i := range want {
+		if tiles[i] != want[i] {
+			t.Fatalf("got %v, want %v", tiles, want)
+		}
+	}
+}
+
+func TestParseTilesErrors(t *testing.T) {
+	for _, notation := range []string{"8z", "0m", "11111m", "12", "m", "1x", "1m2"} {
+		if tiles, err := ParseTiles(notation); err == nil {
+			t.Errorf("ParseTiles(%q) = %v, want an error", notation, tiles)
+		}
+	}
+}
+
+func TestFormatTilesRoundTrip(t *testing.T) {
+	for _, notation := range []string{"", "1m", "123m456p789s1234567z", "11112222333344m", "19m19p19s1234567z"} {
+		if got := FormatTiles(mustParseTiles(t, notation)); got != notation {
+			t.Errorf("FormatTiles(ParseTiles(%q)) = %q", notation, got)
+		}
+	}
+	for id := 0; id < tileInMountNumber; id++ {
+		tiles := mustParseTiles(t, FormatTiles([]Tile{Tile(id)}))
+		if len(tiles) != 1 || tiles[0].Kind() != Tile(id).Kind() {
+			t.Fatalf("tile %d parsed back as %v", id, tiles)
+		}
+	}
+}
+
+func TestFormatTilesSkipsNone(t *testing.T) {
+	tiles := append(mustParseTiles(t, "12m"), TileNone)
+	tiles = append(tiles, mustParseTiles(t, "3m")...)
+	if got := FormatTiles(tiles); got != "12m3m" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestPinfuQueryParse(t *testing.T) {
+	tests := []struct {
+		hands string
+		ronTile string
+		want PinfuQuery
+	}{
+		{"11456789m789p23s", "4s", PinfuQuery{"11456789", "789", "234", "", 27, 27, "sou", "4"}},
+		// all-sou hands are sent as man
+		{"1122334455667s", "7s", PinfuQuery{"11223344556677", "", "", "", 27, 27, "man", "7"}},
+		{"1122334455667m", "7m", PinfuQuery{"11223344556677", "", "", "", 27, 27, "man", "7"}},
+		{"112233445566s7z", "7z", PinfuQuery{"", "", "112233445566", "77", 27, 27, "honors", "7"}},
+		{"123m456p789s1122z", "2z", PinfuQuery{"123", "456", "789", "11222", 27, 27, "honors", "2"}},
+	}
+	for _, test := range tests {
+		p := PinfuQuery{}
+		p.Parse(mustParseTiles(t, test.hands), mustParseTiles(t, test.ronTile)[0], int(EAST), int(EAST))
+		if p != test.want {
+			t.Errorf("%s+%s: got %+v, want %+v", test.hands, test.ronTile, p, test.want)
+		}
+	}
+}
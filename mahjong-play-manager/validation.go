@@ -0,0 +1,119 @@
+package main
+
+import "fmt"
+
+const (
+	errorCodeUnknownOperation = "unknownOperation"
+	errorCodeSpectator = "spectator"
+	errorCodePaused = "paused"
+	errorCodeWrongPhase = "wrongPhase"
+	errorCodeNotYourTurn = "notYourTurn"
+	errorCodeInvalidTarget = "invalidTarget"
+	errorCodeCannotRon = "cannotRon"
+	errorCodeCannotTsumo = "cannotTsumo"
+	errorCodeCannotRiichi = "cannotRiichi"
+	errorCodeRiichiLocked = "riichiLocked"
+	errorCodeFuriten = "furiten"
+	errorCodeClosed = "closed"
+	errorCodeAlreadyDecided = "alreadyDecided"
+)
+
+// OperationError is why an operation was rejected. It is sent only to the client that sent the operation.
+type OperationError struct {
+	Code string `json:"code"`
+	Operation string `json:"operation"`
+	Message string `json:"message"`
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Operation, e.Code, e.Message)
+}
+
+func newOperationError(o *Operator, code string, format string, a ...interface{}) *OperationError {
+	return &OperationError{code, o.Operation, fmt.Sprintf(format, a...)}
+}
+
+// ValidateOperation checks an operation against the seat of the sender and the state of the table.
+// Nothing is changed by a rejected operation.
+func (m *MahjongPlayManager) ValidateOperation(playerId int, o *Operator) *OperationError {
+	if playerId == playerIdNone {
+		return newOperationError(o, errorCodeSpectator, "spectators cannot operate")
+	}
+	if m.IsPaused() && !o.isResume() {
+		return newOperationError(o, errorCodePaused, "the table is paused")
+	}
+	switch {
+	case o.isStart():
+		if m.phase != PhaseWaitingPlayers {
+			return newOperationError(o, errorCodeWrongPhase, "the game has already started")
+		}
+	case o.isDiscard():
+		if m.phase != PhaseWaitingDiscard {
+			return newOperationError(o, errorCodeWrongPhase, "no discard is awaited")
+		}
+		if playerId != m.playerIdInTurn {
+			return newOperationError(o, errorCodeNotYourTurn, "player %d is in turn", m.playerIdInTurn)
+		}
+		if o.Target != tileIdNone && (o.Target < 0 || o.Target >= len(m.playerInfos[playerId].Hands)) {
+			return newOperationError(o, errorCodeInvalidTarget, "no tile at %d", o.Target)
+		}
+		if o.Target != tileIdNone && m.playerInfos[playerId].Riichi {
+			return newOperationError(o, errorCodeRiichiLocked, "only the drawn tile can be discarded after riichi")
+		}
+	case o.isRiichi():
+		if m.phase != PhaseWaitingDiscard {
+			return newOperationError(o, errorCodeWrongPhase, "no discard is awaited")
+		}
+		if playerId != m.playerIdInTurn {
+			return newOperationError(o, errorCodeNotYourTurn, "player %d is in turn", m.playerIdInTurn)
+		}
+		if o.Target != tileIdNone && (o.Target < 0 || o.Target >= len(m.playerInfos[playerId].Hands)) {
+			return newOperationError(o, errorCodeInvalidTarget, "no tile at %d", o.Target)
+		}
+		p := m.playerInfos[playerId]
+		if !m.canDeclareRiichi(p) {
+			return newOperationError(o, errorCodeCannotRiichi, "riichi needs the rule, %d points and %d tiles left", riichiDeposit, riichiMinLiveTiles)
+		}
+		if !m.isTenpaiAfterDiscard(p, o.Target) {
+			return newOperationError(o, errorCodeCannotRiichi, "the hands are not tenpai after the discard")
+		}
+	case o.isTsumo():
+		if m.phase != PhaseWaitingDiscard {
+			return newOperationError(o, errorCodeWrongPhase, "no discard is awaited")
+		}
+		if playerId != m.playerIdInTurn {
+			return newOperationError(o, errorCodeNotYourTurn, "player %d is in turn", m.playerIdInTurn)
+		}
+		if !m.playerInfos[playerId].TsumoInfo.CanWin() {
+			return newOperationError(o, errorCodeCannotTsumo, "the drawn tile does not complete the hands")
+		}
+	case o.isRon(), o.isSkip():
+		if m.phase != PhaseWaitingRon {
+			return newOperationError(o, errorCodeWrongPhase, "no discard can be won")
+		}
+		if playerId != m.playerIdInTurn && o.isRon() && m.IsFuriten(m.playerInfos[playerId]) {
+			return newOperationError(o, errorCodeFuriten, "the hands are furiten")
+		}
+		if playerId == m.playerIdInTurn || !m.playerInfos[playerId].PinfuInfo.CanWin() {
+			return newOperationError(o, errorCodeCannotRon, "the discard does not complete the hands")
+		}
+		if m.HasDecided(playerId) {
+			return newOperationError(o, errorCodeAlreadyDecided, "the discard has already been answered")
+		}
+	case o.isNext():
+		if m.phase != PhaseHandOver {
+			return newOperationError(o, errorCodeWrongPhase, "the round is not over")
+		}
+	case o.isResult():
+		if m.phase != PhaseHandOver || m.continueGame() {
+			return newOperationError(o, errorCodeWrongPhase, "the game is not over")
+		}
+	case o.isResume():
+		if !m.IsPaused() {
+			return newOperationError(o, errorCodeWrongPhase, "the table is not paused")
+		}
+	default:
+		return newOperationError(o, errorCodeUnknownOperation, "unknown operation %q", o.Operation)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+type WaitShape string
+
+const (
+	WaitRyanmen WaitShape = "ryanmen"
+	WaitKanchan WaitShape = "kanchan"
+	WaitPenchan WaitShape = "penchan"
+	WaitTanki WaitShape = "tanki"
+	WaitShanpon WaitShape = "shanpon"
+)
+
+// Wait is a tile kind that completes a tenpai hand and the shapes it can be read as.
+type Wait struct {
+	Tile Tile `json:"tile"`
+	Shapes []WaitShape `json:"shapes"`
+}
+
+// WaitShape is the wait the winning tile completed in this split.
+func (d *Decomposition) WaitShape() WaitShape {
+	if d.WinningMeld == winningMeldPair {
+		return WaitTanki
+	}
+	meld := d.Melds[d.WinningMeld]
+	if meld.Type == MeldTriplet {
+		return WaitShanpon
+	}
+	start := meld.Tiles[0].Kind()
+	switch {
+	case d.WinTile.Kind() == start + 1:
+		return WaitKanchan
+	case isRyanmenWin(start, d.WinTile.Kind()):
+		return WaitRyanmen
+	}
+	return WaitPenchan
+}
+
+// Waits lists every tile kind that completes the tenpai hands with all the wait shapes it completes.
+// The tile of each wait is a copy of the kind that is not in the hands.
+func Waits(hands []Tile) []*Wait {
+	waits := []*Wait{}
+	for _, kind := range winningKinds(hands) {
+		winTile := unusedTile(hands, kind)
+		wait := &Wait{winTile, []WaitShape{}}
+		for _, d := range Decompose(hands, winTile) {
+			wait.Shapes = appendWaitShape(wait.Shapes, d.WaitShape())
+		}
+		waits = append(waits, wait)
+	}
+	return waits
+}
+
+func appendWaitShape(shapes []WaitShape, shape WaitShape) []WaitShape {
+	for _, s := range shapes {
+		if s == shape {
+			return shapes
+		}
+	}
+	return append(shapes, shape)
+}
+
+func unusedTile(hands []Tile, kind int) Tile {
+	used := make([]bool, tileCopyNumber)
+	for _, t := range hands {
+		if t.Kind() == kind {
+			used[t.Copy()] = true
+		}
+	}
+	for copyIndex, u := range used {
+		if !u {
+			return NewTile(kind, copyIndex)
+		}
+	}
+	return TileNone
+}
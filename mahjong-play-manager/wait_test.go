@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func formatWaits(waits []*Wait) string {
+	strs := []string{}
+	for _, w := range waits {
+		strs = append(strs, fmt.Sprintf("%s%v", w.Tile, w.Shapes))
+	}
+	return strings.Join(strs, " ")
+}
+
+func TestWaits(t *testing.T) {
+	tests := []struct {
+		hands string
+		want string
+	}{
+		{"23m456789p123s11z", "1m[ryanmen] 4m[ryanmen]"},
+		{"1113m456789p123s", "2m[kanchan] 3m[tanki]"},
+		{"12m456789p123s11z", "3m[penchan]"},
+		{"11m456789p123s11z", "1m[shanpon] 1z[shanpon]"},
+		{"1112345678999m", "1m[shanpon ryanmen] 2m[tanki] 3m[penchan ryanmen] 4m[ryanmen] 5m[tanki] 6m[ryanmen] 7m[ryanmen penchan] 8m[tanki] 9m[ryanmen shanpon]"},
+		{"1479m258p369s1234z", ""},
+	}
+	for _, test := range tests {
+		if got := formatWaits(Waits(mustParseTiles(t, test.hands))); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.hands, got, test.want)
+		}
+	}
+}
+
+// The wait reported for a win is the one of the split that was scored, not the first pinfu split.
+func TestRonInfoWaitShapeIsScoredSplit(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetAny)
+	m := MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	m.InitRound()
+	winner := (m.playerIdInTurn + 1) % playerNumber
+	p := m.playerInfos[winner]
+	p.Hands = mustParseTiles(t, "3355666777m555s")
+	m.discardedTile = mustParseTiles(t, "5m")[0]
+	p.PinfuInfo = ruleset.EvaluateWin(p.Hands, m.discardedTile, WinSituation{}, p.Wind, m.round.Wind)
+	if !containsYaku(p.PinfuInfo.Yaku, "toitoi") {
+		t.Fatalf("got %v, want the toitoi split to score the most", p.PinfuInfo.Yaku)
+	}
+	r := m.CalculateRonInfo(winner)
+	if r[winner].WaitShape != WaitShanpon {
+		t.Fatalf("got %s, want %s", r[winner].WaitShape, WaitShanpon)
+	}
+}
+
+func TestUpdateWinningTablesSetsWaits(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	m := MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	p := m.playerInfos[0]
+	p.Hands = mustParseTiles(t, "23m456789p123s11z")
+	if err := m.UpdateWinningTables(p.PlayerId); err != nil {
+		t.Fatal(err)
+	}
+	if got := formatWaits(p.Waits); got != "1m[ryanmen] 4m[ryanmen]" {
+		t.Fatalf("got %s", got)
+	}
+}
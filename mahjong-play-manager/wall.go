@@ -0,0 +1,47 @@
+package main
+
+const (
+	deadWallTileNumber = 14
+)
+
+// Wall is the tiles of a hand in drawing order. The last deadWall tiles are the dead wall,
+// which is never drawn, and the rest are the live tiles.
+type Wall struct {
+	tiles []Tile
+	position int
+	deadWall int
+}
+
+// NewWall deals from tiles, keeping a dead wall when hasDeadWall is set.
+func NewWall(tiles []Tile, hasDeadWall bool) *Wall {
+	deadWall := 0
+	if hasDeadWall {
+		deadWall = deadWallTileNumber
+	}
+	return &Wall{tiles, 0, deadWall}
+}
+
+func (w *Wall) Draw() Tile {
+	t := w.tiles[w.position]
+	w.position++
+	return t
+}
+
+func (w *Wall) CanDraw() bool {
+	return w.LiveTileNumber() > 0
+}
+
+// LiveTileNumber is how many tiles can still be drawn.
+func (w *Wall) LiveTileNumber() int {
+	return len(w.tiles) - w.deadWall - w.position
+}
+
+// IsExhausted tells that the last live tile has been drawn, so that a win on the tile
+// drawn last is haitei and a win on the discard after it is houtei.
+func (w *Wall) IsExhausted() bool {
+	return !w.CanDraw()
+}
+
+func (w *Wall) Tiles() []Tile {
+	return w.tiles
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	crypto_rand "crypto/rand"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+const (
+	saltByteNumber = 16
+	seedByteNumber = 32
+)
+
+// WallReveal opens the commitment published with the start of a hand. The wall of the hand is
+// shuffled from Seed, the hex of the hand seed, and Commitment is the SHA-256 of Salt followed
+// by the tile IDs of the wall.
+type WallReveal struct {
+	Commitment string `json:"commitment"`
+	Seed string `json:"seed"`
+	Salt string `json:"salt"`
+}
+
+// NewSeed is a random secret seed of a game.
+func NewSeed() []byte {
+	seed := make([]byte, seedByteNumber)
+	crypto_rand.Read(seed)
+	return seed
+}
+
+// ParseSeed reads the hex of a game seed.
+func ParseSeed(s string) ([]byte, error) {
+	seed, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed: %v", err)
+	}
+	if len(seed) != seedByteNumber {
+		return nil, fmt.Errorf("invalid seed: %d bytes, want %d", len(seed), seedByteNumber)
+	}
+	return seed, nil
+}
+
+// HandSeed is the seed of the wall of a hand, the HMAC-SHA256 of the hand number keyed by
+// the game seed. A revealed hand seed tells nothing about the game seed or the other walls.
+func HandSeed(gameSeed []byte, hand int) []byte {
+	mac := hmac.New(sha256.New, gameSeed)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(hand))
+	mac.Write(b[:])
+	return mac.Sum(nil)
+}
+
+// ShuffleWall is the wall dealt from a hand seed.
+func ShuffleWall(seed []byte) []Tile {
+	wall := make([]Tile, tileInMountNumber)
+	for i := range wall {
+		wall[i] = Tile(i)
+	}
+	s := &wallStream{seed, 0, nil}
+	for i := len(wall) - 1; i > 0; i-- {
+		j := s.Intn(i + 1)
+		wall[i], wall[j] = wall[j], wall[i]
+	}
+	return wall
+}
+
+// wallStream is the random stream of a shuffle: the SHA-256 blocks of the seed followed by a counter.
+type wallStream struct {
+	seed []byte
+	counter uint64
+	block []byte
+}
+
+func (s *wallStream) Uint32() uint32 {
+	if len(s.block) < 4 {
+		h := sha256.New()
+		h.Write(s.seed)
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], s.counter)
+		h.Write(b[:])
+		s.block = h.Sum(nil)
+		s.counter++
+	}
+	v := binary.BigEndian.Uint32(s.block)
+	s.block = s.block[4:]
+	return v
+}
+
+// Intn is uniform in [0, n), drawing again when a value would bias it.
+func (s *wallStream) Intn(n int) int {
+	limit := math.MaxUint32 - math.MaxUint32 % uint32(n)
+	for {
+		if v := s.Uint32(); v < limit {
+			return int(v % uint32(n))
+		}
+	}
+}
+
+// CommitWall hashes the wall with a salt, so that the commitment tells nothing about the wall.
+func CommitWall(salt []byte, wall []Tile) string {
+	h := sha256.New()
+	h.Write(salt)
+	for _, t := range wall {
+		h.Write([]byte{byte(t)})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NewWallReveal commits to the wall of a hand seed with a new random salt.
+func NewWallReveal(seed []byte) *WallReveal {
+	salt := make([]byte, saltByteNumber)
+	crypto_rand.Read(salt)
+	return &WallReveal{CommitWall(salt, ShuffleWall(seed)), hex.EncodeToString(seed), hex.EncodeToString(salt)}
+}
+
+// Verify rebuilds the wall from the seed and checks it against the commitment.
+func (r *WallReveal) Verify() ([]Tile, error) {
+	salt, err := hex.DecodeString(r.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %v", err)
+	}
+	seed, err := hex.DecodeString(r.Seed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed: %v", err)
+	}
+	wall := ShuffleWall(seed)
+	commitment := CommitWall(salt, wall)
+	if commitment != r.Commitment {
+		return wall, fmt.Errorf("commitment mismatch: %s, rebuilt %s", r.Commitment, commitment)
+	}
+	return wall, nil
+}
+
+func LoadWallReveal(path string) (*WallReveal, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reveal := &WallReveal{}
+	if err := json.Unmarshal(body, reveal); err != nil {
+		return nil, err
+	}
+	return reveal, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWallRevealVerifies(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	m := &MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	if err := m.Operate(0, &Operator{"start", tileIdNone}); err != nil {
+		t.Fatal(err)
+	}
+	start := string(m.sendMessages[0].ToBytes())
+	if !strings.Contains(start, m.wallReveal.Commitment) || strings.Contains(start, m.wallReveal.Salt) {
+		t.Fatalf("the start message should carry the commitment but not the salt: %s", start)
+	}
+
+	// the reveal goes through JSON as a client would get it
+	b, err := json.Marshal(m.wallReveal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reveal := &WallReveal{}
+	if err := json.Unmarshal(b, reveal); err != nil {
+		t.Fatal(err)
+	}
+	wall, err := reveal.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(wall, m.wall.Tiles()) {
+		t.Fatalf("the rebuilt wall %s is not the dealt wall %s", FormatTiles(wall), FormatTiles(m.wall.Tiles()))
+	}
+}
+
+func TestWallRevealTampered(t *testing.T) {
+	reveal := NewWallReveal(HandSeed(bytes.Repeat([]byte{1}, seedByteNumber), 0))
+	if _, err := reveal.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	salt, _ := hex.DecodeString(reveal.Salt)
+	salt[0] ^= 1
+	tampered := *reveal
+	tampered.Salt = hex.EncodeToString(salt)
+	if _, err := tampered.Verify(); err == nil {
+		t.Error("a tampered salt was verified")
+	}
+
+	seed, _ := hex.DecodeString(reveal.Seed)
+	seed[0] ^= 1
+	tampered = *reveal
+	tampered.Seed = hex.EncodeToString(seed)
+	if _, err := tampered.Verify(); err == nil {
+		t.Error("a tampered seed was verified")
+	}
+
+	tampered = *reveal
+	tampered.Salt = "not hex"
+	if _, err := tampered.Verify(); err == nil {
+		t.Error("an invalid salt was verified")
+	}
+}
+
+func TestWallRevealAtHandEnd(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	m := &MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	if err := m.Operate(0, &Operator{"start", tileIdNone}); err != nil {
+		t.Fatal(err)
+	}
+	for m.Phase() != PhaseHandOver {
+		if m.Phase() == PhaseWaitingRon {
+			m.TimeoutRonWindow(m.ronWindow)
+			continue
+		}
+		if err := m.Operate(m.playerIdInTurn, &Operator{"discard", tileIdNone}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, s := range m.sendMessages {
+		if !strings.Contains(string(s.ToBytes()), m.wallReveal.Salt) {
+			t.Errorf("player %d got no reveal at the hand end: %s", i, s.ToBytes())
+		}
+	}
+}
+
+func TestHandSeeds(t *testing.T) {
+	gameSeed := bytes.Repeat([]byte{1}, seedByteNumber)
+	seen := map[string]bool{}
+	for hand := 0; hand < 8; hand++ {
+		seed := HandSeed(gameSeed, hand)
+		if !bytes.Equal(seed, HandSeed(gameSeed, hand)) {
+			t.Fatalf("hand %d: the seed is not deterministic", hand)
+		}
+		if seen[string(seed)] {
+			t.Fatalf("hand %d: the seed of an earlier hand", hand)
+		}
+		seen[string(seed)] = true
+
+		wall := ShuffleWall(seed)
+		counts := make([]int, tileInMountNumber)
+		for _, tile := range wall {
+			counts[tile]++
+		}
+		for tile, count := range counts {
+			if count != 1 {
+				t.Fatalf("hand %d: tile %d is in the wall %d times", hand, tile, count)
+			}
+		}
+	}
+	if bytes.Equal(HandSeed(gameSeed, 0), HandSeed(bytes.Repeat([]byte{2}, seedByteNumber), 0)) {
+		t.Fatal("two game seeds give the same hand seed")
+	}
+}
+
+func TestParseSeed(t *testing.T) {
+	seed := NewSeed()
+	parsed, err := ParseSeed(hex.EncodeToString(seed))
+	if err != nil || !bytes.Equal(parsed, seed) {
+		t.Fatal(parsed, err)
+	}
+	for _, s := range []string{"", "12345", "not hex", hex.EncodeToString(seed[1:])} {
+		if _, err := ParseSeed(s); err == nil {
+			t.Errorf("%q: want an error", s)
+		}
+	}
+}
+
+// Each hand of a game deals the wall of its own hand seed.
+func TestHandsDealFromHandSeeds(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	m := &MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	gameSeed := NewSeed()
+	m.SetSeed(gameSeed)
+	for hand := 0; hand < 3; hand++ {
+		m.InitWall()
+		if !reflect.DeepEqual(m.wall.Tiles(), ShuffleWall(HandSeed(gameSeed, hand))) {
+			t.Fatalf("hand %d: the wall is not the wall of the hand seed", hand)
+		}
+		if m.wallReveal.Seed != hex.EncodeToString(HandSeed(gameSeed, hand)) {
+			t.Fatalf("hand %d: got reveal seed %s", hand, m.wallReveal.Seed)
+		}
+	}
+}
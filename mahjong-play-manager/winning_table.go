@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// WinningTable maps each tile kind that completes the hands to what the win is worth.
+// Kinds that do not win are not stored.
+type WinningTable map[int]*PinfuInfo
+
+type winningTableQuery struct {
+	playerId int
+	kind int
+	pinfuInfo *PinfuInfo
+}
+
+func (t WinningTable) Lookup(tile Tile) *PinfuInfo {
+	if pinfuInfo, ok := t[tile.Kind()]; ok {
+		return pinfuInfo
+	}
+	return &PinfuInfo{false, 0, 0, 0, nil, ""}
+}
+
+// UpdateWinningTables rebuilds the tables of the given players. Only the kinds that complete
+// the hands are sent to the evaluator, so hands that are not tenpai cost no evaluation.
+// The first query is evaluated before the others, so that an evaluator whose circuit breaker
+// is half-open gets its single trial call through before the rest are sent.
+// The tables are left untouched when any evaluation fails.
+func (m *MahjongPlayManager) UpdateWinningTables(playerIds ...int) error {
+	queries := []*winningTableQuery{}
+	for _, playerId := range playerIds {
+		for _, kind := range winningKinds(m.playerInfos[playerId].Hands) {
+			queries = append(queries, &winningTableQuery{playerId, kind, nil})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), evaluationTimeout)
+	defer cancel()
+	rest := queries
+	if len(queries) > 0 {
+		if err := m.evaluateWinningTableQuery(ctx, queries[0]); err != nil {
+			return err
+		}
+		rest = queries[1:]
+	}
+	errs := make(chan error, len(queries))
+	var wg sync.WaitGroup
+	for _, q := range rest {
+		wg.Add(1)
+		go func(q *winningTableQuery) {
+			defer wg.Done()
+			if err := m.evaluateWinningTableQuery(ctx, q); err != nil {
+				errs <- err
+				cancel()
+			}
+		}(q)
+	}
+	wg.Wait()
+	close(errs)
+	if err, ok := <-errs; ok {
+		return err
+	}
+
+	for _, playerId := range playerIds {
+		m.playerInfos[playerId].WinningTable = WinningTable{}
+		m.playerInfos[playerId].Waits = Waits(m.playerInfos[playerId].Hands)
+	}
+	for _, q := range queries {
+		if q.pinfuInfo.CanWin() {
+			m.playerInfos[q.playerId].WinningTable[q.kind] = q.pinfuInfo
+		}
+	}
+	return nil
+}
+
+func (m *MahjongPlayManager) evaluateWinningTableQuery(ctx context.Context, q *winningTableQuery) error {
+	p := m.playerInfos[q.playerId]
+	pinfuInfo, err := m.handEvaluator.Evaluate(ctx, p.Hands, NewTile(q.kind, 0), WinSituation{}, p.Wind, m.round.Wind)
+	if err != nil {
+		return err
+	}
+	q.pinfuInfo = pinfuInfo
+	return nil
+}
+
+func winningKinds(hands []Tile) []int {
+	counts := tileKindCounts(hands)
+	kinds := []int{}
+	for kind := range counts {
+		if counts[kind] == tileCopyNumber {
+			continue
+		}
+		counts[kind]++
+		if isCompleteHand(counts) {
+			kinds = append(kinds, kind)
+		}
+		counts[kind]--
+	}
+	return kinds
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomTenpaiHands removes one tile from a random complete hand of four melds and a pair.
+func randomTenpaiHands(r *rand.Rand) []Tile {
+	for {
+		counts := make([]int, tileKindNumber)
+		for i := 0; i < 4; i++ {
+			if r.Intn(3) == 0 {
+				counts[r.Intn(tileKindNumber)] += 3
+				continue
+			}
+			start := r.Intn(3)*tileInSuitNumber + r.Intn(tileInSuitNumber - 2)
+			counts[start]++
+			counts[start + 1]++
+			counts[start + 2]++
+		}
+		counts[r.Intn(tileKindNumber)] += 2
+		if !isValidCounts(counts) {
+			continue
+		}
+		tiles := []Tile{}
+		for kind, count := range counts {
+			for i := 0; i < count; i++ {
+				tiles = append(tiles, NewTile(kind, i))
+			}
+		}
+		i := r.Intn(len(tiles))
+		return append(tiles[:i], tiles[i + 1:]...)
+	}
+}
+
+func isValidCounts(counts []int) bool {
+	for _, count := range counts {
+		if count > tileCopyNumber {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWinningTableMatchesEvaluation(t *testing.T) {
+	for _, yakuSet := range []string{yakuSetPinfu, yakuSetAny} {
+		ruleset := testRuleset(t, yakuSet)
+		m := MahjongPlayManager{}
+		m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+		r := rand.New(rand.NewSource(1))
+		wins := 0
+		for i := 0; i < 500; i++ {
+			p := m.playerInfos[r.Intn(playerNumber)]
+			p.Hands = randomTenpaiHands(r)
+			if err := m.UpdateWinningTables(p.PlayerId); err != nil {
+				t.Fatal(err)
+			}
+			counts := tileKindCounts(p.Hands)
+			for id := 0; id < tileInMountNumber; id++ {
+				tile := Tile(id)
+				if counts[tile.Kind()] == tileCopyNumber {
+					continue
+				}
+				want, err := m.handEvaluator.Evaluate(context.Background(), p.Hands, tile, WinSituation{}, p.Wind, m.round.Wind)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := p.WinningTable.Lookup(tile)
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("%s: %s+%s got %+v, want %+v", yakuSet, FormatTiles(p.Hands), tile, got, want)
+				}
+				if got.CanWin() {
+					wins++
+				}
+			}
+		}
+		if wins == 0 {
+			t.Fatalf("%s: no winning hands were generated", yakuSet)
+		}
+	}
+}
+
+func TestWinningTableOnlyStoresWins(t *testing.T) {
+	ruleset := testRuleset(t, yakuSetPinfu)
+	m := MahjongPlayManager{}
+	m.Init(&NativeHandEvaluator{ruleset}, ruleset)
+	p := m.playerInfos[1]
+	p.Hands = mustParseTiles(t, "11456789m789p23s")
+	if err := m.UpdateWinningTables(p.PlayerId); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.WinningTable) != 2 || !p.WinningTable.Lookup(mustParseTiles(t, "1s")[0]).IsPinfu || !p.WinningTable.Lookup(mustParseTiles(t, "4s")[0]).IsPinfu {
+		t.Fatalf("got %v, want pinfu on 1s and 4s", p.WinningTable)
+	}
+	p.Hands = mustParseTiles(t, "1479m258p369s1234z")
+	if err := m.UpdateWinningTables(p.PlayerId); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.WinningTable) != 0 {
+		t.Fatalf("got %v for a hand that is not tenpai", p.WinningTable)
+	}
+}
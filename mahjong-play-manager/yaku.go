@@ -0,0 +1,353 @@
+package main
+
+import "fmt"
+
+const (
+	yakuPinfu = "pinfu"
+	yakuTanyao = "tanyao"
+	yakuIipeikou = "iipeikou"
+	yakuRyanpeikou = "ryanpeikou"
+	yakuYakuhai = "yakuhai"
+	yakuSanshoku = "sanshoku"
+	yakuIttsu = "ittsu"
+	yakuChanta = "chanta"
+	yakuJunchan = "junchan"
+	yakuHonitsu = "honitsu"
+	yakuChinitsu = "chinitsu"
+	yakuToitoi = "toitoi"
+	yakuSanankou = "sanankou"
+	yakuMenzenTsumo = "menzen_tsumo"
+	yakuHaitei = "haitei"
+	yakuHoutei = "houtei"
+	yakuRiichi = "riichi"
+	yakuIppatsu = "ippatsu"
+)
+
+// WinSituation is how a win is made apart from its tiles. IsLastTile tells that the wall
+// is exhausted, so the win is on the last drawn tile or the discard after it.
+type WinSituation struct {
+	IsTsumo bool
+	IsLastTile bool
+	IsRiichi bool
+	IsIppatsu bool
+}
+
+// WinContext is what a yaku can look at: one split of the winning hand and how it was won.
+// Every hand is closed, so the han are the closed values.
+type WinContext struct {
+	Decomposition *Decomposition
+	WinSituation
+	PlayerWind Wind
+	RoundWind Wind
+}
+
+// Yaku gives the han it is worth for a win, or 0 when the win does not have it.
+type Yaku interface {
+	Name() string
+	Han(w *WinContext) int
+}
+
+var yakuRegistry = []Yaku{}
+
+// RegisterYaku adds a yaku that rulesets can then name. Names must be unique.
+func RegisterYaku(y Yaku) {
+	if _, ok := FindYaku(y.Name()); ok {
+		panic(fmt.Sprintf("yaku registered twice: %s", y.Name()))
+	}
+	yakuRegistry = append(yakuRegistry, y)
+}
+
+func FindYaku(name string) (Yaku, bool) {
+	for _, y := range yakuRegistry {
+		if y.Name() == name {
+			return y, true
+		}
+	}
+	return nil, false
+}
+
+// YakuNames lists the registered yaku in registration order.
+func YakuNames() []string {
+	names := []string{}
+	for _, y := range yakuRegistry {
+		names = append(names, y.Name())
+	}
+	return names
+}
+
+func init() {
+	RegisterYaku(&yakuFunc{yakuPinfu, hanPinfuYaku})
+	RegisterYaku(&yakuFunc{yakuTanyao, hanTanyao})
+	RegisterYaku(&yakuFunc{yakuIipeikou, hanIipeikou})
+	RegisterYaku(&yakuFunc{yakuRyanpeikou, hanRyanpeikou})
+	RegisterYaku(&yakuFunc{yakuYakuhai, hanYakuhai})
+	RegisterYaku(&yakuFunc{yakuSanshoku, hanSanshoku})
+	RegisterYaku(&yakuFunc{yakuIttsu, hanIttsu})
+	RegisterYaku(&yakuFunc{yakuChanta, hanChanta})
+	RegisterYaku(&yakuFunc{yakuJunchan, hanJunchan})
+	RegisterYaku(&yakuFunc{yakuHonitsu, hanHonitsu})
+	RegisterYaku(&yakuFunc{yakuChinitsu, hanChinitsu})
+	RegisterYaku(&yakuFunc{yakuToitoi, hanToitoi})
+	RegisterYaku(&yakuFunc{yakuSanankou, hanSanankou})
+	RegisterYaku(&yakuFunc{yakuMenzenTsumo, hanMenzenTsumo})
+	RegisterYaku(&yakuFunc{yakuHaitei, hanHaitei})
+	RegisterYaku(&yakuFunc{yakuHoutei, hanHoutei})
+	RegisterYaku(&yakuFunc{yakuRiichi, hanRiichi})
+	RegisterYaku(&yakuFunc{yakuIppatsu, hanIppatsu})
+}
+
+type yakuFunc struct {
+	name string
+	han func(w *WinContext) int
+}
+
+func (y *yakuFunc) Name() string {
+	return y.name
+}
+
+func (y *yakuFunc) Han(w *WinContext) int {
+	return y.han(w)
+}
+
+func hanPinfuYaku(w *WinContext) int {
+	if w.Decomposition.IsPinfu(w.PlayerWind, w.RoundWind) {
+		return hanPinfu
+	}
+	return 0
+}
+
+func hanTanyao(w *WinContext) int {
+	for _, g := range w.Decomposition.groups() {
+		for _, t := range g {
+			if isTerminalOrHonorKind(t.Kind()) {
+				return 0
+			}
+		}
+	}
+	return 1
+}
+
+// identicalSequencePairs counts the pairs of sequences with the same tiles.
+func identicalSequencePairs(d *Decomposition) int {
+	starts := make([]int, tileKindNumber)
+	pairs := 0
+	for _, m := range d.Melds {
+		if m.Type == MeldSequence {
+			starts[m.Tiles[0].Kind()]++
+			if starts[m.Tiles[0].Kind()]%2 == 0 {
+				pairs++
+			}
+		}
+	}
+	return pairs
+}
+
+func hanIipeikou(w *WinContext) int {
+	if identicalSequencePairs(w.Decomposition) == 1 {
+		return 1
+	}
+	return 0
+}
+
+func hanRyanpeikou(w *WinContext) int {
+	if identicalSequencePairs(w.Decomposition) == 2 {
+		return 3
+	}
+	return 0
+}
+
+// hanYakuhai is one han for each dragon triplet and for each of the player and round wind triplets.
+func hanYakuhai(w *WinContext) int {
+	han := 0
+	for _, m := range w.Decomposition.Melds {
+		if m.Type != MeldTriplet {
+			continue
+		}
+		kind := m.Tiles[0].Kind()
+		if kind >= tileKindDragonStart {
+			han++
+		}
+		if kind == windKind(w.PlayerWind) {
+			han++
+		}
+		if kind == windKind(w.RoundWind) {
+			han++
+		}
+	}
+	return han
+}
+
+func hanSanshoku(w *WinContext) int {
+	suits := make([][]bool, tileInSuitNumber)
+	for rank := range suits {
+		suits[rank] = make([]bool, SuitHonor)
+	}
+	for _, m := range w.Decomposition.Melds {
+		if m.Type == MeldSequence {
+			suits[m.Tiles[0].Rank() - 1][m.Tiles[0].Suit()] = true
+		}
+	}
+	for _, s := range suits {
+		if s[SuitMan] && s[SuitPin] && s[SuitSou] {
+			return 2
+		}
+	}
+	return 0
+}
+
+func hanIttsu(w *WinContext) int {
+	starts := make([]bool, tileKindHonorStart)
+	for _, m := range w.Decomposition.Melds {
+		if m.Type == MeldSequence {
+			starts[m.Tiles[0].Kind()] = true
+		}
+	}
+	for suitStart := 0; suitStart < tileKindHonorStart; suitStart += tileInSuitNumber {
+		if starts[suitStart] && starts[suitStart + 3] && starts[suitStart + 6] {
+			return 2
+		}
+	}
+	return 0
+}
+
+// outsideHand reports whether every group holds a terminal or an honor, and whether the hand has
+// any honor. Hands of only triplets are left to other yaku.
+func outsideHand(d *Decomposition) (bool, bool) {
+	hasSequence := false
+	hasHonor := false
+	for _, g := range d.groups() {
+		outside := false
+		for _, t := range g {
+			if isTerminalOrHonorKind(t.Kind()) {
+				outside = true
+			}
+			if t.Suit() == SuitHonor {
+				hasHonor = true
+			}
+		}
+		if !outside {
+			return false, false
+		}
+	}
+	for _, m := range d.Melds {
+		if m.Type == MeldSequence {
+			hasSequence = true
+		}
+	}
+	return hasSequence, hasHonor
+}
+
+func hanChanta(w *WinContext) int {
+	if outside, hasHonor := outsideHand(w.Decomposition); outside && hasHonor {
+		return 2
+	}
+	return 0
+}
+
+func hanJunchan(w *WinContext) int {
+	if outside, hasHonor := outsideHand(w.Decomposition); outside && !hasHonor {
+		return 3
+	}
+	return 0
+}
+
+// flushSuit is the only suit in the hand apart from honors, whether the hand has honors,
+// and false when more than one suit is in it.
+func flushSuit(d *Decomposition) (Suit, bool, bool) {
+	suit := SuitHonor
+	hasHonor := false
+	for _, g := range d.groups() {
+		for _, t := range g {
+			switch {
+			case t.Suit() == SuitHonor:
+				hasHonor = true
+			case suit == SuitHonor:
+				suit = t.Suit()
+			case suit != t.Suit():
+				return suit, hasHonor, false
+			}
+		}
+	}
+	return suit, hasHonor, true
+}
+
+func hanHonitsu(w *WinContext) int {
+	if suit, hasHonor, ok := flushSuit(w.Decomposition); ok && hasHonor && suit != SuitHonor {
+		return 3
+	}
+	return 0
+}
+
+func hanChinitsu(w *WinContext) int {
+	if _, hasHonor, ok := flushSuit(w.Decomposition); ok && !hasHonor {
+		return 6
+	}
+	return 0
+}
+
+func hanToitoi(w *WinContext) int {
+	for _, m := range w.Decomposition.Melds {
+		if m.Type != MeldTriplet {
+			return 0
+		}
+	}
+	return 2
+}
+
+// hanSanankou counts the concealed triplets, where a triplet completed by ron is not concealed.
+func hanSanankou(w *WinContext) int {
+	concealed := 0
+	for i, m := range w.Decomposition.Melds {
+		if m.Type == MeldTriplet && (w.IsTsumo || i != w.Decomposition.WinningMeld) {
+			concealed++
+		}
+	}
+	if concealed >= 3 {
+		return 2
+	}
+	return 0
+}
+
+func hanMenzenTsumo(w *WinContext) int {
+	if w.IsTsumo {
+		return 1
+	}
+	return 0
+}
+
+func hanHaitei(w *WinContext) int {
+	if w.IsTsumo && w.IsLastTile {
+		return 1
+	}
+	return 0
+}
+
+func hanHoutei(w *WinContext) int {
+	if !w.IsTsumo && w.IsLastTile {
+		return 1
+	}
+	return 0
+}
+
+func hanRiichi(w *WinContext) int {
+	if w.IsRiichi {
+		return 1
+	}
+	return 0
+}
+
+func hanIppatsu(w *WinContext) int {
+	if w.IsRiichi && w.IsIppatsu {
+		return 1
+	}
+	return 0
+}
+
+// groups lists the tiles of every meld and then the pair.
+func (d *Decomposition) groups() [][]Tile {
+	groups := [][]Tile{}
+	for _, m := range d.Melds {
+		groups = append(groups, m.Tiles)
+	}
+	return append(groups, d.Pair)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestYakuHan wins each hand with a ruleset of the one yaku, so the han are those of the yaku
+// on its best split and 0 when no split has it.
+func TestYakuHan(t *testing.T) {
+	tests := []struct {
+		yaku string
+		hands string
+		winTile string
+		situation WinSituation
+		playerWind Wind
+		roundWind Wind
+		want int
+	}{
+		{yakuTanyao, "234567m234p56s88s", "7s", WinSituation{}, SOUTH, EAST, 1},
+		{yakuTanyao, "123456m234p56s88s", "7s", WinSituation{}, SOUTH, EAST, 0},
+		{yakuTanyao, "234567m234p567s1z", "1z", WinSituation{}, SOUTH, EAST, 0},
+
+		// iipeikou and ryanpeikou do not count together
+		{yakuIipeikou, "223344m567p2388s", "4s", WinSituation{}, SOUTH, EAST, 1},
+		{yakuIipeikou, "234567m234p56s88s", "7s", WinSituation{}, SOUTH, EAST, 0},
+		{yakuIipeikou, "223344m556677p8s", "8s", WinSituation{}, SOUTH, EAST, 0},
+		{yakuRyanpeikou, "223344m556677p8s", "8s", WinSituation{}, SOUTH, EAST, 3},
+		{yakuRyanpeikou, "223344m567p2388s", "4s", WinSituation{}, SOUTH, EAST, 0},
+
+		{yakuYakuhai, "23m456p789s555z99s", "1m", WinSituation{}, SOUTH, EAST, 1},
+		{yakuYakuhai, "23m456p789s222z99s", "1m", WinSituation{}, SOUTH, EAST, 1},
+		{yakuYakuhai, "23m456p789s111z99s", "1m", WinSituation{}, EAST, EAST, 2},
+		{yakuYakuhai, "23m456p789s333z99s", "1m", WinSituation{}, SOUTH, EAST, 0},
+		{yakuYakuhai, "23m456p789s123s55z", "1m", WinSituation{}, SOUTH, EAST, 0},
+
+		{yakuSanshoku, "123m123p123s789s5z", "5z", WinSituation{}, SOUTH, EAST, 2},
+		{yakuSanshoku, "123m123p234s789s5z", "5z", WinSituation{}, SOUTH, EAST, 0},
+
+		{yakuIttsu, "123456789m11p55z", "5z", WinSituation{}, SOUTH, EAST, 2},
+		{yakuIttsu, "123456m789p11p55z", "5z", WinSituation{}, SOUTH, EAST, 0},
+
+		// chanta needs an honor and junchan none, and neither counts without a sequence
+		{yakuChanta, "123m789p123s789s5z", "5z", WinSituation{}, SOUTH, EAST, 2},
+		{yakuChanta, "123m789p123s789s9m", "9m", WinSituation{}, SOUTH, EAST, 0},
+		{yakuChanta, "123m789p234s789s5z", "5z", WinSituation{}, SOUTH, EAST, 0},
+		{yakuChanta, "111m999p111s999s1z", "1z", WinSituation{}, SOUTH, EAST, 0},
+		{yakuJunchan, "123m789p123s789s9m", "9m", WinSituation{}, SOUTH, EAST, 3},
+		{yakuJunchan, "123m789p123s789s5z", "5z", WinSituation{}, SOUTH, EAST, 0},
+		{yakuJunchan, "123m789p234s789s9m", "9m", WinSituation{}, SOUTH, EAST, 0},
+
+		// honitsu needs an honor and chinitsu none
+		{yakuHonitsu, "123456789m11m55z", "5z", WinSituation{}, SOUTH, EAST, 3},
+		{yakuHonitsu, "1112345678999m", "5m", WinSituation{}, SOUTH, EAST, 0},
+		{yakuHonitsu, "123456789m11p55z", "5z", WinSituation{}, SOUTH, EAST, 0},
+		{yakuHonitsu, "111z222z333z444z5z", "5z", WinSituation{}, SOUTH, EAST, 0},
+		{yakuChinitsu, "1112345678999m", "5m", WinSituation{}, SOUTH, EAST, 6},
+		{yakuChinitsu, "123456789m11m55z", "5z", WinSituation{}, SOUTH, EAST, 0},
+		{yakuChinitsu, "123456789m11p55z", "5z", WinSituation{}, SOUTH, EAST, 0},
+
+		{yakuToitoi, "111m222p999s11z22z", "2z", WinSituation{}, SOUTH, EAST, 2},
+		{yakuToitoi, "123m222p999s11z22z", "2z", WinSituation{}, SOUTH, EAST, 0},
+
+		// a triplet completed by ron is not concealed
+		{yakuSanankou, "111m222p999s345s1z", "1z", WinSituation{}, SOUTH, EAST, 2},
+		{yakuSanankou, "111m222p345s11z22z", "2z", WinSituation{}, SOUTH, EAST, 0},
+		{yakuSanankou, "111m222p345s11z22z", "2z", WinSituation{IsTsumo: true}, SOUTH, EAST, 2},
+	}
+	for _, test := range tests {
+		ruleset := testRuleset(t, yakuSetPinfu)
+		ruleset.Yaku = []string{test.yaku}
+		info := ruleset.EvaluateWin(mustParseTiles(t, test.hands), mustParseTiles(t, test.winTile)[0], test.situation, test.playerWind, test.roundWind)
+		if info.Han != test.want {
+			t.Errorf("%s on %s+%s: got %d han, want %d", test.yaku, test.hands, test.winTile, info.Han, test.want)
+		}
+	}
+}
+
+func TestEvaluateWinPicksHighestScoringSplit(t *testing.T) {
+	tests := []struct {
+		hands string
+		winTile string
+		want []string
+		han int
+		fu int
+	}{
+		// three triplets for sanankou score above three sequences for iipeikou
+		{"111222333m456p9s", "9s", []string{yakuSanankou}, 2, 50},
+		{"223344m556677p8s", "8s", []string{yakuTanyao, yakuRyanpeikou}, 4, 40},
+		// 9m completing 78m for pinfu beats the tanki wait and the three triplets
+		{"111222333789m9m", "9m", []string{yakuPinfu, yakuIipeikou, yakuJunchan, yakuChinitsu}, 11, 30},
+	}
+	for _, test := range tests {
+		ruleset := testRuleset(t, yakuSetAny)
+		info := ruleset.EvaluateWin(mustParseTiles(t, test.hands), mustParseTiles(t, test.winTile)[0], WinSituation{}, SOUTH, EAST)
+		if !reflect.DeepEqual(info.Yaku, test.want) || info.Han != test.han || info.Fu != test.fu {
+			t.Errorf("%s+%s: got %v %d han %d fu, want %v %d han %d fu", test.hands, test.winTile, info.Yaku, info.Han, info.Fu, test.want, test.han, test.fu)
+		}
+	}
+}
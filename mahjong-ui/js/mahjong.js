@@ -73,6 +73,25 @@ class Mahjong {
     static get MODAL_DURATION() {
         return 2000;
     }
+
+    static get TILE_NAMES() {
+        return [
+            '一萬', '二萬', '三萬', '四萬', '五萬', '六萬', '七萬', '八萬', '九萬',
+            '一筒', '二筒', '三筒', '四筒', '五筒', '六筒', '七筒', '八筒', '九筒',
+            '一索', '二索', '三索', '四索', '五索', '六索', '七索', '八索', '九索',
+            '東', '南', '西', '北', '白', '發', '中'
+        ];
+    }
+
+    static get WAIT_SHAPES() {
+        return {
+            ryanmen: '両面',
+            kanchan: '嵌張',
+            penchan: '辺張',
+            tanki: '単騎',
+            shanpon: '双碰'
+        };
+    }
 }
 
 class MahjongManager {
@@ -87,6 +106,7 @@ class MahjongManager {
         this.roundRonModal = new RoundResultModal('#modal-round-result');
         this.gameResultModal = new GameResultModal('#modal-game-result');
         this.operationButton = new OperationButton();
+        this.notice = new Notice();
         this.webSocketManager = new WebSocketManager(this);
 
         $('#hands-tile-self').on('click', (event) => this.discard(event));
@@ -112,6 +132,8 @@ class MahjongManager {
         mahjongManager.showDrawnTile();
         mahjongManager.clearHo();
         mahjongManager.operationButton.hideButton();
+        mahjongManager.operationButton.showTsumoButton(playInfo.playerInfo.canTsumo);
+        mahjongManager.operationButton.showRiichiButton(playInfo.playerInfo.canRiichi);
     }
 
     setRound(round) {
@@ -200,6 +222,23 @@ class MahjongManager {
     updatePlayerHands(info) {
         this.players[0].hands = info.hands;
         this.players[0].drawnTile = info.drawnTile;
+        this.showWaits(info.waits);
+    }
+
+    showWaits(waits) {
+        var text = "";
+        if (waits && waits.length > 0) {
+            text = "待ち";
+            waits.forEach(function(wait) {
+                var shapes = wait.shapes.map(function(shape) {
+                    return Mahjong.WAIT_SHAPES[shape];
+                });
+                text += " " + Mahjong.TILE_NAMES[Math.floor(wait.tile/4)] + "(" + shapes.join("・") + ")";
+            });
+        }
+        $('#waits-self').each(function(item) {
+            item.innerHTML = text;
+        });
     }
 
     showHands() {
@@ -224,8 +263,17 @@ class MahjongManager {
         });
     }
 
-    showRoundRonModal() {
-        this.roundRonModal.showModal("和了");
+    showRoundRonModal(ronInfo) {
+        var title = "和了";
+        ronInfo.forEach(function(ron) {
+            if (ron.waitShape) {
+                title += " " + Mahjong.WAIT_SHAPES[ron.waitShape];
+            }
+            if (ron.score) {
+                title += " " + ron.score.han + "翻" + ron.score.fu + "符";
+            }
+        });
+        this.roundRonModal.showModal(title);
         this.roundRonModal.setModalTimeout(this.webSocketManager);
     }
 
@@ -602,27 +650,79 @@ class OperationButton {
             item.classList.add("display-none");
         });
     }
+
+    showTsumoButton(canTsumo) {
+        this.toggleButton('#tsumo', canTsumo);
+    }
+
+    showRiichiButton(canRiichi) {
+        this.toggleButton('#riichi', canRiichi);
+    }
+
+    toggleButton(selector, visible) {
+        $(selector).each(function(item) {
+            if (visible) {
+                item.classList.remove("display-none");
+            } else {
+                item.classList.add("display-none");
+            }
+        });
+    }
+}
+
+class Notice {
+    show(message, canResume) {
+        $('.notice-message').each(function(item) {
+            item.innerHTML = message;
+        });
+        $('#resume').each(function(item) {
+            if (canResume) {
+                item.classList.remove("display-none");
+            } else {
+                item.classList.add("display-none");
+            }
+        });
+        $('#notice').each(function(item) {
+            item.classList.remove("display-none");
+        });
+    }
+
+    hide() {
+        $('#notice').each(function(item) {
+            item.classList.add("display-none");
+        });
+    }
 }
 
 class WebSocketManager {
     constructor(mahjongManager) {
         var self = this;
         self.mahjongManager = mahjongManager;
+        self.riichiDeclared = false;
         this.messageHandlers = [
             {type: "start", handler: this.receiveStart},
+            {type: "seatState", handler: this.receiveSeatState},
             {type: "discard", handler: this.receiveDiscard},
             {type: "drawn", handler: this.receiveDrawn},
             {type: "discardOther", handler: this.receiveDiscardOther},
             {type: "ron", handler: this.receiveRon},
+            {type: "tsumo", handler: this.receiveRon},
             {type: "skip", handler: this.receiveSkip},
             {type: "drawnRound", handler: this.receiveDrawnRound},
             {type: "next", handler: this.receiveNext},
-            {type: "result", handler: this.receiveResult}
+            {type: "result", handler: this.receiveResult},
+            {type: "pause", handler: this.receivePause},
+            {type: "spectator", handler: this.receiveSpectator},
+            {type: "seat", handler: this.receiveSeat},
+            {type: "error", handler: this.receiveError}
         ];
         if (window["WebSocket"]) {
-            self.conn = new WebSocket("ws://" + document.location.host + "/ws");
+            self.conn = new WebSocket("ws://" + document.location.host + "/ws" + document.location.search);
             self.conn.onmessage = function (evt) {
                 var message = JSON.parse(evt.data);
+                if (message["type"] != "pause") {
+                    mahjongManager.notice.hide();
+                }
                 self.messageHandlers.forEach(function(item) {
                     if (item.type == message["type"]) {
                         console.log("received message type:" + message["type"]);
@@ -635,6 +735,9 @@ class WebSocketManager {
         $('#tile-drawn-self').on('click', (event) => this.sendDiscard(event));
         $('#ron').on('click', (event) => this.sendRon(event, mahjongManager));
         $('#skip').on('click', (event) => this.sendSkip(event, mahjongManager));
+        $('#tsumo').on('click', (event) => this.sendTsumo(event, mahjongManager));
+        $('#riichi').on('click', (event) => this.declareRiichi(event, mahjongManager));
+        $('#resume').on('click', (event) => this.sendResume(event));
         $('#debug-start').on('click', (event) => this.debugStart(event));
         $('#debug-discard-tile').on('click', (event) => this.debugDiscardTile(event));
         $('#debug-ron').on('click', (event) => this.debugRon(event));
@@ -644,14 +747,32 @@ class WebSocketManager {
 
     receiveStart(mahjongManager, playInfo) {
         console.log(playInfo);
+        console.log("wall commitment:" + playInfo.wallCommitment);
         mahjongManager.setPlayersId(playInfo.playerIds);
         mahjongManager.initRound(playInfo);
     }
 
+    receiveSeatState(mahjongManager, playInfo) {
+        console.log(playInfo);
+        mahjongManager.setPlayersId(playInfo.playerIds);
+        mahjongManager.initRound(playInfo);
+        playInfo.rivers.forEach(function(river, position) {
+            river.forEach(function(tile) {
+                mahjongManager.players[position].discardOther(tile);
+            });
+            mahjongManager.players[position].showHo();
+        });
+        if (playInfo.canRon) {
+            mahjongManager.operationButton.showButton();
+        }
+    }
+
     receiveDiscard(mahjongManager, playerInfo) {
         console.log(playerInfo);
         mahjongManager.updatePlayerHands(playerInfo);
         mahjongManager.showHands();
+        mahjongManager.operationButton.showTsumoButton(false);
+        mahjongManager.operationButton.showRiichiButton(false);
     }
 
     receiveDrawn(mahjongManager, playerInfo) {
@@ -661,11 +782,14 @@ class WebSocketManager {
         mahjongManager.players[3].discardOther(playerInfo.discardedTileUp);
         mahjongManager.players[3].showHo();
         mahjongManager.operationButton.hideButton();
+        mahjongManager.operationButton.showTsumoButton(playerInfo.canTsumo);
+        mahjongManager.operationButton.showRiichiButton(playerInfo.canRiichi);
     }
 
     receiveDiscardOther(mahjongManager, discardedTileInfo) {
         console.log("discard other position:" + discardedTileInfo.playerPosition);
         console.log("discard other tile:" + discardedTileInfo.discardedTile);
+        console.log("discard other riichi:" + discardedTileInfo.riichi);
         if (discardedTileInfo.canRon) {
             mahjongManager.operationButton.showButton();
         }
@@ -673,10 +797,13 @@ class WebSocketManager {
         mahjongManager.players[discardedTileInfo.playerPosition].showHo();
     }
 
-    receiveRon(mahjongManager, ronInfo) {
-        console.log(ronInfo);
+    receiveRon(mahjongManager, ronRoundInfo) {
+        console.log(ronRoundInfo);
+        console.log("wall reveal:" + JSON.stringify(ronRoundInfo.wallReveal));
+        var ronInfo = ronRoundInfo.ronInfo;
+        mahjongManager.operationButton.showTsumoButton(false);
         mahjongManager.updatePlayerPoints(ronInfo);
-        mahjongManager.showRoundRonModal();
+        mahjongManager.showRoundRonModal(ronInfo);
         mahjongManager.updatePointsByRonInfo(ronInfo);
         mahjongManager.showPoint();
     }
@@ -688,6 +815,7 @@ class WebSocketManager {
 
     receiveDrawnRound(mahjongManager, drawnRoundInfo) {
         console.log(drawnRoundInfo);
+        console.log("wall reveal:" + JSON.stringify(drawnRoundInfo.wallReveal));
         var playerPosition = drawnRoundInfo.discardedTileInfo.playerPosition;
         if (playerPosition != 0) {
             mahjongManager.players[playerPosition].discardOther(drawnRoundInfo.discardedTileInfo.discardedTile);
@@ -698,6 +826,7 @@ class WebSocketManager {
     }
 
     receiveNext(mahjongManager, playInfo) {
+        console.log("wall commitment:" + playInfo.wallCommitment);
         mahjongManager.initRound(playInfo);
     }
 
@@ -709,7 +838,9 @@ class WebSocketManager {
     sendDiscard(event) {
         if (this.mahjongManager.canDiscard()) {
             console.log("send:" + event.target.value);
-            this.conn.send(JSON.stringify({operation: "discard", target: event.target.value}));
+            var operation = this.riichiDeclared ? "riichi" : "discard";
+            this.riichiDeclared = false;
+            this.conn.send(JSON.stringify({operation: operation, target: event.target.value}));
         }
     }
 
@@ -719,12 +850,52 @@ class WebSocketManager {
         this.conn.send(JSON.stringify({operation: "ron", target: -1}));
     }
 
+    sendTsumo(event, mahjongManager) {
+        console.log("send tsumo");
+        mahjongManager.operationButton.showTsumoButton(false);
+        this.conn.send(JSON.stringify({operation: "tsumo", target: -1}));
+    }
+
+    declareRiichi(event, mahjongManager) {
+        console.log("declare riichi");
+        mahjongManager.operationButton.showRiichiButton(false);
+        this.riichiDeclared = true;
+    }
+
     sendSkip(event, mahjongManager) {
         console.log("send skip");
         mahjongManager.operationButton.hideButton();
         this.conn.send(JSON.stringify({operation: "skip", target: -1}));
     }
 
+    receivePause(mahjongManager, pauseInfo) {
+        console.log("pause");
+        mahjongManager.notice.show("和了判定ができないため中断しています", true);
+    }
+
+    receiveSpectator(mahjongManager, seatInfo) {
+        console.log(seatInfo);
+        mahjongManager.notice.show("満席のため観戦中です(空き待ち" + seatInfo.waitingPosition + "番目)", false);
+    }
+
+    receiveSeat(mahjongManager, seatInfo) {
+        console.log("seat:" + seatInfo.playerId);
+    }
+
+    receiveError(mahjongManager, operationError) {
+        console.log(operationError);
+        if (operationError.operation == "next") {
+            // every seat sends next when the round modal closes and only the first one is taken
+            return;
+        }
+        mahjongManager.notice.show("操作できません(" + operationError.code + ")", false);
+    }
+
+    sendResume(event) {
+        console.log("send resume");
+        this.conn.send(JSON.stringify({operation: "resume", target: -1}));
+    }
+
     sendNext() {
         this.conn.send(JSON.stringify({operation: "next", target: -1}));
     }